@@ -3,8 +3,12 @@ module HiveServer
 go 1.23.2
 
 require (
-	github.com/go-test/deep v1.1.1 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/crypto v0.31.0
 )
+
+require github.com/go-test/deep v1.1.1
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,112 @@
+package hivegame
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+)
+
+// Symmetry maps the positions of a game onto those of its canonical form. Positions are first
+// rotated/reflected by Matrix and then translated by Offset.
+type Symmetry struct {
+	Matrix HexMatrixInt
+	Offset HexVectorInt
+}
+
+func (s Symmetry) Apply(v HexVectorInt) HexVectorInt {
+	return s.Matrix.Transform(v).Add(s.Offset)
+}
+
+// Invert maps a position of the canonical form back onto the original game
+func (s Symmetry) Invert(v HexVectorInt) HexVectorInt {
+	return s.Matrix.Inverse().Transform(v.Subtract(s.Offset))
+}
+
+// Canonical returns the representative of every game that differs from this one only by
+// translation, rotation or reflection, along with the symmetry that takes this game onto it.
+//
+// The tiles of the canonical form are sorted and translated such that the first tile sits at the
+// origin. Of the twelve rotations and reflections, the one with the smallest sorted tile list is
+// chosen.
+func (game *HiveGame) Canonical() (HiveGame, Symmetry) {
+	canonical, symmetries := game.canonicalSymmetries()
+	return canonical, symmetries[0]
+}
+
+// canonicalSymmetries is Canonical, but returns every symmetry that takes this game onto its
+// canonical form. There is more than one when the position is itself symmetric.
+func (game *HiveGame) canonicalSymmetries() (HiveGame, []Symmetry) {
+	canonical := game.Clone()
+
+	if len(game.Tiles) == 0 {
+		return canonical, []Symmetry{{Matrix: Identity()}}
+	}
+
+	var best []HiveTile
+	var symmetries []Symmetry
+
+	for _, matrix := range HexSymmetries() {
+		tiles := make([]HiveTile, len(game.Tiles))
+
+		for i, tile := range game.Tiles {
+			tile.Position = matrix.Transform(tile.Position)
+			tiles[i] = tile
+		}
+
+		slices.SortFunc(tiles, compareTiles)
+
+		offset := HexVectorInt{}.Subtract(tiles[0].Position)
+		for i := range tiles {
+			tiles[i].Position = tiles[i].Position.Add(offset)
+		}
+
+		symmetry := Symmetry{Matrix: matrix, Offset: offset}
+
+		if best == nil {
+			best = tiles
+			symmetries = []Symmetry{symmetry}
+			continue
+		}
+
+		switch slices.CompareFunc(tiles, best, compareTiles) {
+		case -1:
+			best = tiles
+			symmetries = []Symmetry{symmetry}
+		case 0:
+			symmetries = append(symmetries, symmetry)
+		}
+	}
+
+	canonical.Tiles = best
+
+	return canonical, symmetries
+}
+
+// CanonicalHash identifies a position up to symmetry. Two games share a hash when their canonical
+// forms have the same tiles and the same colour to move; the move number is not considered.
+func (game *HiveGame) CanonicalHash() uint64 {
+	canonical, _ := game.Canonical()
+
+	buffer := make([]byte, 0, 1+5*binary.MaxVarintLen64*len(canonical.Tiles))
+	buffer = binary.AppendVarint(buffer, int64(canonical.ColorToMove))
+
+	for _, tile := range canonical.Tiles {
+		buffer = binary.AppendVarint(buffer, int64(tile.Position.Q))
+		buffer = binary.AppendVarint(buffer, int64(tile.Position.R))
+		buffer = binary.AppendVarint(buffer, int64(tile.StackHeight))
+		buffer = binary.AppendVarint(buffer, int64(tile.Color))
+		buffer = binary.AppendVarint(buffer, int64(tile.PieceType))
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(buffer)
+
+	return hash.Sum64()
+}
+
+func compareTiles(a, b HiveTile) int {
+	keysA := [5]int{a.Position.R, a.Position.Q, a.StackHeight, a.Color, a.PieceType}
+	keysB := [5]int{b.Position.R, b.Position.Q, b.StackHeight, b.Color, b.PieceType}
+
+	return slices.Compare(keysA[:], keysB[:])
+}
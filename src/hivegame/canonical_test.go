@@ -0,0 +1,109 @@
+package hivegame
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestHexSymmetriesPreserveAdjacency(t *testing.T) {
+	for i, matrix := range HexSymmetries() {
+		for _, adj := range (HexVectorInt{}).AdjacentVectors() {
+			transformed := matrix.Transform(adj)
+
+			found := false
+			for _, other := range (HexVectorInt{}).AdjacentVectors() {
+				if other == transformed {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				t.Fatalf("Symmetry %d maps adjacent vector %v to non-adjacent %v", i, adj, transformed)
+			}
+		}
+
+		if matrix.Multiply(matrix.Inverse()) != Identity() {
+			t.Fatalf("Symmetry %d multiplied by its inverse is not the identity", i)
+		}
+	}
+}
+
+func TestCanonicalIgnoresTranslationRotationAndReflection(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, -1}, PieceTypeSoldierAnt)
+	game.PlaceTile(HexVectorInt{-2, 1}, PieceTypeSpider)
+
+	expected, _ := game.Canonical()
+
+	for i, matrix := range HexSymmetries() {
+		moved := game.Clone()
+		for j := range moved.Tiles {
+			moved.Tiles[j].Position = matrix.Transform(moved.Tiles[j].Position).Add(HexVectorInt{3, -7})
+		}
+
+		actual, _ := moved.Canonical()
+
+		if diff := deep.Equal(expected.Tiles, actual.Tiles); diff != nil {
+			t.Fatalf("Canonical form differs under symmetry %d: %v", i, diff)
+		}
+
+		if moved.CanonicalHash() != game.CanonicalHash() {
+			t.Fatalf("Canonical hash differs under symmetry %d", i)
+		}
+	}
+}
+
+func TestCanonicalSymmetryMapsPositions(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{2, 5}, PieceTypeGrasshopper)
+	game.PlaceTile(HexVectorInt{3, 5}, PieceTypeBeetle)
+	game.PlaceTile(HexVectorInt{1, 5}, PieceTypeQueenBee)
+
+	canonical, symmetry := game.Canonical()
+
+	for _, tile := range game.Tiles {
+		mapped := symmetry.Apply(tile.Position)
+
+		if symmetry.Invert(mapped) != tile.Position {
+			t.Fatalf("Inverting the symmetry did not return %v", tile.Position)
+		}
+
+		found := false
+		for _, canonicalTile := range canonical.Tiles {
+			if canonicalTile.Position == mapped && canonicalTile.PieceType == tile.PieceType {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Fatalf("Tile at %v was not mapped onto the canonical form", tile.Position)
+		}
+	}
+}
+
+func TestCanonicalHashDistinguishesPositions(t *testing.T) {
+	a := CreateHiveGame()
+	a.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	a.PlaceTile(HexVectorInt{1, 0}, PieceTypeQueenBee)
+
+	b := CreateHiveGame()
+	b.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	b.PlaceTile(HexVectorInt{1, 0}, PieceTypeSpider)
+
+	if a.CanonicalHash() == b.CanonicalHash() {
+		t.Fatalf("Different positions must not share a canonical hash")
+	}
+
+	c := a.Clone()
+	c.ColorToMove = ColorWhite
+
+	if a.CanonicalHash() == c.CanonicalHash() {
+		t.Fatalf("Positions with different colours to move must not share a canonical hash")
+	}
+}
@@ -58,3 +58,63 @@ func Rotate300() HexMatrixInt {
 		1, 1,
 	}
 }
+
+func Identity() HexMatrixInt {
+	return HexMatrixInt{
+		1, 0,
+		0, 1,
+	}
+}
+
+// Reflect mirrors a vector across the axis running through {1, -1}, by swapping the q and r
+// components
+func Reflect() HexMatrixInt {
+	return HexMatrixInt{
+		0, 1,
+		1, 0,
+	}
+}
+
+func (m HexMatrixInt) Multiply(n HexMatrixInt) HexMatrixInt {
+	return HexMatrixInt{
+		A00: m.A00*n.A00 + m.A01*n.A10,
+		A01: m.A00*n.A01 + m.A01*n.A11,
+		A10: m.A10*n.A00 + m.A11*n.A10,
+		A11: m.A10*n.A01 + m.A11*n.A11,
+	}
+}
+
+func (m HexMatrixInt) Determinant() int {
+	return m.A00*m.A11 - m.A01*m.A10
+}
+
+// Inverse is only defined for the matrices with a determinant of 1 or -1, which includes every
+// rotation and reflection of the grid
+func (m HexMatrixInt) Inverse() HexMatrixInt {
+	det := m.Determinant()
+
+	if det != 1 && det != -1 {
+		panic("cannot invert a hex matrix that is not a symmetry of the grid")
+	}
+
+	return HexMatrixInt{
+		A00: det * m.A11,
+		A01: -det * m.A01,
+		A10: -det * m.A10,
+		A11: det * m.A00,
+	}
+}
+
+// HexSymmetries returns the twelve rotations and reflections of the hex grid about the origin
+func HexSymmetries() [12]HexMatrixInt {
+	var symmetries [12]HexMatrixInt
+
+	rotation := Identity()
+	for i := range 6 {
+		symmetries[i] = rotation
+		symmetries[i+6] = rotation.Multiply(Reflect())
+		rotation = Rotate60().Multiply(rotation)
+	}
+
+	return symmetries
+}
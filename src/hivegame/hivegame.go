@@ -1,5 +1,10 @@
 package hivegame
 
+import (
+	"iter"
+	"slices"
+)
+
 type HiveColor = int
 
 const (
@@ -61,6 +66,26 @@ func CreateHiveGame() HiveGame {
 	}
 }
 
+// Clone returns a deep copy of the game that can be played on without affecting the original
+func (game *HiveGame) Clone() HiveGame {
+	clone := *game
+
+	clone.Tiles = make([]HiveTile, len(game.Tiles))
+	copy(clone.Tiles, game.Tiles)
+
+	clone.WhiteReserve = make(map[HivePieceType]int, len(game.WhiteReserve))
+	for pieceType, count := range game.WhiteReserve {
+		clone.WhiteReserve[pieceType] = count
+	}
+
+	clone.BlackReserve = make(map[HivePieceType]int, len(game.BlackReserve))
+	for pieceType, count := range game.BlackReserve {
+		clone.BlackReserve[pieceType] = count
+	}
+
+	return clone
+}
+
 func (game *HiveGame) PlaceTile(position HexVectorInt, pieceType HivePieceType) bool {
 	for _, tile := range game.Tiles {
 		if tile.Position == position {
@@ -69,15 +94,7 @@ func (game *HiveGame) PlaceTile(position HexVectorInt, pieceType HivePieceType)
 		}
 	}
 
-	queenPlaced := false
-	for _, tile := range game.Tiles {
-		if tile.Color == game.ColorToMove && tile.PieceType == PieceTypeQueenBee {
-			queenPlaced = true
-			break
-		}
-	}
-
-	if !queenPlaced && game.Move == 4 && pieceType != PieceTypeQueenBee {
+	if !game.queenPlaced(game.ColorToMove) && game.Move == 4 && pieceType != PieceTypeQueenBee {
 		return false
 	}
 
@@ -153,106 +170,86 @@ func (game *HiveGame) PlaceTile(position HexVectorInt, pieceType HivePieceType)
 	})
 
 	game.incrementMove()
-	game.skipIfNoLegalMoves()
 
 	return true
 }
 
 func (game *HiveGame) MoveTile(from, to HexVectorInt) bool {
-	fromTile := game.tileAt(from)
-
-	if fromTile == nil {
-		// cannot move a tile which is not in play
-		return false
-	}
-
-	if fromTile.Color != game.ColorToMove {
-		return false
-	}
-
-	if game.isTilePinned(fromTile) {
-		// cannot move a tile if doing such would create multiple hives
-		return false
-	}
-
-	var moves map[HexVectorInt]bool
+	reachable := false
 
-	switch fromTile.PieceType {
-	case PieceTypeQueenBee:
-		moves = game.queenBeeMoves(fromTile.Position)
-	case PieceTypeSoldierAnt:
-		moves = game.soldierAntMoves(fromTile.Position)
-	case PieceTypeSpider:
-		moves = game.spiderMoves(fromTile.Position)
-	case PieceTypeGrasshopper:
-		moves = game.grasshopperMoves(fromTile.Position)
-	case PieceTypeLadybug:
-		moves = game.ladybugMoves(fromTile.Position)
-	case PieceTypeBeetle:
-		moves = game.beetleMoves(fromTile.Position)
-	case PieceTypeMosquito:
-		moves = game.mosquitoMoves(fromTile.Position)
-	default:
-		panic("unhandled case")
+	for destination := range game.destinations(from) {
+		if destination == to {
+			reachable = true
+			break
+		}
 	}
 
-	if _, ok := moves[to]; !ok {
+	if !reachable {
 		// not reachable via the rules
 		return false
 	}
 
+	fromTile := game.tileAt(from)
 	fromTile.StackHeight = game.nextStackHeight(to)
 	fromTile.Position = to
 	game.incrementMove()
-	game.skipIfNoLegalMoves()
 	return true
 }
 
 func (game *HiveGame) LegalMoves(from HexVectorInt) []HexVectorInt {
-	fromTile := game.tileAt(from)
+	return slices.AppendSeq(make([]HexVectorInt, 0), game.LegalMovesSeq(from))
+}
 
-	if fromTile == nil {
-		// cannot move a tile which is not in play
-		return make([]HexVectorInt, 0)
-	}
+// destinations yields every position the top tile at 'from' can move to, which is none at all if
+// there is no such tile or it cannot be moved by the colour to move
+func (game *HiveGame) destinations(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		fromTile := game.tileAt(from)
 
-	if fromTile.Color != game.ColorToMove {
-		return make([]HexVectorInt, 0)
-	}
+		if fromTile == nil {
+			// cannot move a tile which is not in play
+			return
+		}
 
-	if game.isTilePinned(fromTile) {
-		// cannot move a tile if doing such would create multiple hives
-		return make([]HexVectorInt, 0)
-	}
+		if fromTile.Color != game.ColorToMove {
+			return
+		}
 
-	var moves map[HexVectorInt]bool
+		if !game.queenPlaced(game.ColorToMove) {
+			// no tile may move until its player has placed their queen
+			return
+		}
 
-	switch fromTile.PieceType {
+		if game.isTilePinned(fromTile) {
+			// cannot move a tile if doing such would create multiple hives
+			return
+		}
+
+		game.pieceMoves(fromTile.PieceType, fromTile.Position)(yield)
+	}
+}
+
+// pieceMoves yields the positions a piece of the given type at 'from' could move to, ignoring
+// whether it is free to leave
+func (game *HiveGame) pieceMoves(pieceType HivePieceType, from HexVectorInt) iter.Seq[HexVectorInt] {
+	switch pieceType {
 	case PieceTypeQueenBee:
-		moves = game.queenBeeMoves(fromTile.Position)
+		return game.queenBeeMoves(from)
 	case PieceTypeSoldierAnt:
-		moves = game.soldierAntMoves(fromTile.Position)
+		return game.soldierAntMoves(from)
 	case PieceTypeSpider:
-		moves = game.spiderMoves(fromTile.Position)
+		return game.spiderMoves(from)
 	case PieceTypeGrasshopper:
-		moves = game.grasshopperMoves(fromTile.Position)
+		return game.grasshopperMoves(from)
 	case PieceTypeLadybug:
-		moves = game.ladybugMoves(fromTile.Position)
+		return game.ladybugMoves(from)
 	case PieceTypeBeetle:
-		moves = game.beetleMoves(fromTile.Position)
+		return game.beetleMoves(from)
 	case PieceTypeMosquito:
-		moves = game.mosquitoMoves(fromTile.Position)
+		return game.mosquitoMoves(from)
 	default:
 		panic("unhandled case")
 	}
-
-	movesSlice := make([]HexVectorInt, 0)
-
-	for move := range moves {
-		movesSlice = append(movesSlice, move)
-	}
-
-	return movesSlice
 }
 
 func (game *HiveGame) IsOver() (over bool, winner HiveColor) {
@@ -290,77 +287,39 @@ func (game *HiveGame) IsOver() (over bool, winner HiveColor) {
 	return true, color
 }
 
-func (game *HiveGame) skipIfNoLegalMoves() {
-	queenBeePlaced := false
+// MustPass reports whether the colour to move has no legal moves, in which case it can only pass
+func (game *HiveGame) MustPass() bool {
+	if game.Move == 1 {
+		return false
+	}
 
-	for _, tile := range game.Tiles {
-		if tile.Color == game.ColorToMove && tile.PieceType == PieceTypeQueenBee {
-			queenBeePlaced = true
-			break
-		}
+	if over, _ := game.IsOver(); over {
+		return false
 	}
 
-	if game.Move == 1 || !queenBeePlaced {
-		return
+	for range game.legalTurnsSeq() {
+		return false
 	}
 
-	legalMovements := false
+	return true
+}
 
-	for i, tile := range game.Tiles {
-		if tile.Color == game.ColorToMove {
-			if !game.isTilePinned(&game.Tiles[i]) && len(game.LegalMoves(tile.Position)) > 0 {
-				legalMovements = true
-				break
-			}
-		}
+// Pass gives the turn to the opponent. It is only legal when the colour to move has no other
+// legal moves.
+func (game *HiveGame) Pass() bool {
+	if !game.MustPass() {
+		return false
 	}
 
-	if legalMovements || len(game.LegalPlacements()) > 0 {
-		return
-	}
 	game.incrementMove()
+	return true
 }
 
 func (game *HiveGame) LegalPlacements() map[HexVectorInt]bool {
-	perimeter := make([]HexVectorInt, 0)
-
-	if game.Move == 1 && game.ColorToMove == ColorBlack {
-		// The player can play anywhere, but a stupid question (someone calling this function for a
-		// new game) warrants a stupid answer (this return value)
-		return map[HexVectorInt]bool{}
-	} else if game.Move == 1 {
-		moves := map[HexVectorInt]bool{}
-		for _, p := range game.Tiles[0].Position.AdjacentVectors() {
-			moves[p] = true
-		}
-		return moves
-	}
-
-	for _, tile := range game.Tiles {
-		if tile.Color == game.ColorToMove {
-			for _, adj := range tile.Position.AdjacentVectors() {
-				if game.tileAt(adj) == nil {
-					perimeter = append(perimeter, adj)
-				}
-			}
-		}
-	}
-
 	legalPlacements := make(map[HexVectorInt]bool)
 
-	for _, position := range perimeter {
-		neighboursOpponent := false
-		for _, adj := range position.AdjacentVectors() {
-			adjacentTile := game.tileAt(adj)
-			if adjacentTile != nil && adjacentTile.Color != game.ColorToMove {
-				neighboursOpponent = true
-				break
-			}
-		}
-
-		if !neighboursOpponent {
-			legalPlacements[position] = true
-		}
+	for position := range game.LegalPlacementsSeq() {
+		legalPlacements[position] = true
 	}
 
 	return legalPlacements
@@ -379,6 +338,16 @@ func (game *HiveGame) incrementMove() {
 
 }
 
+func (game *HiveGame) queenPlaced(color HiveColor) bool {
+	for _, tile := range game.Tiles {
+		if tile.Color == color && tile.PieceType == PieceTypeQueenBee {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (game *HiveGame) tileAt(position HexVectorInt) *HiveTile {
 	greatestStackHeight := -1
 	var found *HiveTile = nil
@@ -510,282 +479,212 @@ func (game *HiveGame) isTilePinned(gameTile *HiveTile) bool {
 	return len(seen) != len(game.Tiles)-1
 }
 
-func (game *HiveGame) soldierAntMoves(from HexVectorInt) map[HexVectorInt]bool {
-	seen := make(map[HexVectorInt]bool)
-	toExplore := make([]HexVectorInt, 0)
-
-	toExplore = append(toExplore, from)
+func (game *HiveGame) soldierAntMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		seen := map[HexVectorInt]bool{from: true}
+		toExplore := []HexVectorInt{from}
 
-	for len(toExplore) > 0 {
-		node := toExplore[0]
-		toExplore = toExplore[1:]
-
-		if _, ok := seen[node]; ok {
-			continue
-		}
+		for len(toExplore) > 0 {
+			node := toExplore[0]
+			toExplore = toExplore[1:]
 
-		next := game.adjacentMoves(node, from)
+			for _, next := range game.adjacentMoves(node, from) {
+				if seen[next] {
+					continue
+				}
 
-		toExplore = append(toExplore, next...)
+				seen[next] = true
 
-		seen[node] = true
-	}
+				if !yield(next) {
+					return
+				}
 
-	if _, ok := seen[from]; ok {
-		// cannot move a tile to where it started
-		delete(seen, from)
+				toExplore = append(toExplore, next)
+			}
+		}
 	}
-
-	return seen
 }
 
-func (game *HiveGame) queenBeeMoves(from HexVectorInt) map[HexVectorInt]bool {
-	moves := game.adjacentMoves(from, from)
-	asMap := make(map[HexVectorInt]bool)
-
-	for _, move := range moves {
-		asMap[move] = true
-	}
-
-	return asMap
+func (game *HiveGame) queenBeeMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return slices.Values(game.adjacentMoves(from, from))
 }
 
-func (game *HiveGame) spiderMoves(from HexVectorInt) map[HexVectorInt]bool {
+func (game *HiveGame) spiderMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
 	const SpiderMoveDistance = 3
-	type searchNode struct {
-		previous []HexVectorInt
-		position HexVectorInt
-	}
-
-	search := make([]searchNode, 0)
-	search = append(search, searchNode{previous: make([]HexVectorInt, 0), position: from})
-
-	for range SpiderMoveDistance {
-		for nodeIndex, node := range search {
-			moves := game.adjacentMoves(node.position, from)
-			newMoves := make([]HexVectorInt, 0, 6)
-			for _, move := range moves {
-				seenMove := false
-				for _, previousMove := range node.previous {
-					if move == previousMove {
-						seenMove = true
-						break
-					}
-				}
 
-				if seenMove {
-					continue
+	return func(yield func(HexVectorInt) bool) {
+		// exactly three steps around the hive, never going back over a position already walked
+		var path [SpiderMoveDistance + 1]HexVectorInt
+		path[0] = from
+
+		yielded := make([]HexVectorInt, 0, 6)
+
+		var walk func(steps int) bool
+		walk = func(steps int) bool {
+			if steps == SpiderMoveDistance {
+				end := path[steps]
+				if slices.Contains(yielded, end) {
+					return true
 				}
 
-				newMoves = append(newMoves, move)
+				yielded = append(yielded, end)
+				return yield(end)
 			}
 
-			for _, move := range newMoves {
-				newPrevious := make([]HexVectorInt, 0, SpiderMoveDistance)
-
-				for _, p := range node.previous {
-					newPrevious = append(newPrevious, p)
+			for _, next := range game.adjacentMoves(path[steps], from) {
+				if slices.Contains(path[:steps+1], next) {
+					continue
 				}
 
-				newPrevious = append(newPrevious, node.position)
-
-				newNode := searchNode{previous: newPrevious, position: move}
+				path[steps+1] = next
 
-				search = append(search, newNode)
+				if !walk(steps + 1) {
+					return false
+				}
 			}
 
-			search = append(search[:nodeIndex], search[nodeIndex+1:]...)
+			return true
 		}
-	}
 
-	validMoves := make(map[HexVectorInt]bool)
-	for _, move := range search {
-		if move.position != from {
-			validMoves[move.position] = true
-		}
+		walk(0)
 	}
-
-	return validMoves
 }
 
-func (game *HiveGame) grasshopperMoves(from HexVectorInt) map[HexVectorInt]bool {
-	adjacentTiles := from.AdjacentVectors()
-	neighbours := make([]HexVectorInt, 0, 6)
-	for _, tile := range game.Tiles {
-		for _, adj := range adjacentTiles {
-			if adj == tile.Position {
+func (game *HiveGame) grasshopperMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		// one neighbour per occupied position, however high it is stacked, so that no jump is
+		// yielded twice
+		neighbours := make([]HexVectorInt, 0, 6)
+		for _, adj := range from.AdjacentVectors() {
+			if game.tileAt(adj) != nil {
 				neighbours = append(neighbours, adj)
-				break
 			}
 		}
-	}
 
-	moves := make(map[HexVectorInt]bool)
+		for _, neighbour := range neighbours {
+			direction := neighbour.Subtract(from)
+			const LoopMax = 26 // computed as the greatest distance a hopper could ever jump + 1
+			exitedEarly := false
 
-	for _, neighbour := range neighbours {
-		direction := neighbour.Subtract(from)
-		const LoopMax = 26 // computed as the greatest distance a hopper could ever jump + 1
-		exitedEarly := false
+			// we have already implicitly checked i = 1 by finding 'neighbour'
+			for i := 2; i <= LoopMax; i++ {
+				toCheck := direction.MultiplyScalar(i).Add(from)
 
-		// we have already implicitly checked i = 1 by finding 'neighbour'
-		for i := 2; i <= LoopMax; i++ {
-			toCheck := direction.MultiplyScalar(i).Add(from)
+				occupied := false
+				for _, tile := range game.Tiles {
+					if tile.Position == toCheck {
+						occupied = true
+						break
+					}
+				}
+
+				if !occupied {
+					exitedEarly = true
+
+					if !yield(toCheck) {
+						return
+					}
 
-			occupied := false
-			for _, tile := range game.Tiles {
-				if tile.Position == toCheck {
-					occupied = true
 					break
 				}
 			}
 
-			if !occupied {
-				exitedEarly = true
-				moves[toCheck] = true
-				break
+			if !exitedEarly {
+				panic("in no case should a hopper jump more than 25 tiles")
 			}
 		}
-
-		if !exitedEarly {
-			panic("in no case should a hopper jump more than 25 tiles")
-		}
 	}
-
-	return moves
 }
 
-func (game *HiveGame) ladybugMoves(from HexVectorInt) map[HexVectorInt]bool {
-	// do the same as the spider but enforce that it must be on top of another tile for the first
-	// two moves
-
-	type searchNode struct {
-		previous []HexVectorInt
-		position HexVectorInt
-	}
-
-	search := make([]searchNode, 0)
-	search = append(search, searchNode{previous: make([]HexVectorInt, 0), position: from})
+func (game *HiveGame) ladybugMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		// two steps on top of the hive, then one step down from it, where every step must fit
+		// through the gates on either side of it
+		mover := game.tileAt(from)
+		yielded := make([]HexVectorInt, 0, 6)
 
-	for moveIndex := 0; moveIndex < 2; moveIndex++ {
-		for nodeIndex := len(search) - 1; nodeIndex >= 0; nodeIndex-- {
-			node := search[nodeIndex]
-			potentialNextMoves := make([]HexVectorInt, 0, 6)
-
-			for _, adj := range node.position.AdjacentVectors() {
-				isOccupied := false
-
-				for _, tile := range game.Tiles {
-					if tile.Position == adj {
-						isOccupied = true
-						break
-					}
-				}
-
-				if isOccupied {
-					potentialNextMoves = append(potentialNextMoves, adj)
-				}
+		for _, first := range from.AdjacentVectors() {
+			if game.heightAt(first, mover) == 0 || !game.canStep(from, first, mover) {
+				continue
 			}
 
-			for _, move := range potentialNextMoves {
-				alreadyMovedHere := false
-				for _, pastPosition := range node.previous {
-					if pastPosition == move {
-						alreadyMovedHere = true
-						break
-					}
+			for _, second := range first.AdjacentVectors() {
+				// 'from' is empty once the ladybug has left it, so this also stops it doubling back
+				if game.heightAt(second, mover) == 0 || !game.canStep(first, second, mover) {
+					continue
 				}
 
-				if !alreadyMovedHere {
-					newNode := searchNode{previous: make([]HexVectorInt, 0, 2), position: move}
+				for _, third := range second.AdjacentVectors() {
+					if third == from || game.heightAt(third, mover) != 0 || !game.canStep(second, third, mover) {
+						continue
+					}
 
-					for _, previousMove := range node.previous {
-						newNode.previous = append(newNode.previous, previousMove)
+					if slices.Contains(yielded, third) {
+						continue
 					}
 
-					newNode.previous = append(newNode.previous, node.position)
+					yielded = append(yielded, third)
 
-					search = append(search, newNode)
+					if !yield(third) {
+						return
+					}
 				}
 			}
-
-			search = append(search[:nodeIndex], search[nodeIndex+1:]...)
 		}
 	}
+}
 
-	// now search for unoccupied positions
-	validMoves := make(map[HexVectorInt]bool)
-	for _, node := range search {
-		potentialNextMoves := make([]HexVectorInt, 0, 6)
-		for _, adj := range node.position.AdjacentVectors() {
-			isOccupied := false
-
-			for _, tile := range game.Tiles {
-				if tile.Position == adj {
-					isOccupied = true
-					break
-				}
-			}
+func (game *HiveGame) beetleMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		// the piece at 'from' is the one moving, whichever it is; mosquitos borrow this too
+		mover := game.tileAt(from)
 
-			if !isOccupied {
-				potentialNextMoves = append(potentialNextMoves, adj)
-			}
+		if mover == nil {
+			return
 		}
 
-		// because the current position is "stacked", anything here is a valid move
-		for _, move := range potentialNextMoves {
-			validMoves[move] = true
+		for _, adj := range from.AdjacentVectors() {
+			if game.canStep(from, adj, mover) && !yield(adj) {
+				return
+			}
 		}
 	}
-
-	return validMoves
 }
 
-func (game *HiveGame) beetleMoves(from HexVectorInt) map[HexVectorInt]bool {
-	validMoves := make(map[HexVectorInt]bool)
+// canStep reports whether the mover can take a single step between adjacent positions, at whatever
+// height either end of the step is.
+//
+// Along the ground, the step must slide between its two flanking positions, exactly one of which
+// is occupied so that the mover stays in contact with the hive. Off the ground, the step is only
+// blocked by a gate: both flanking stacks being higher than the mover at either end of the step.
+func (game *HiveGame) canStep(from, to HexVectorInt, mover *HiveTile) bool {
+	direction := to.Subtract(from)
+	clockwise := Rotate60().Transform(direction).Add(from)
+	antiClockwise := Rotate300().Transform(direction).Add(from)
 
-	greatestStackHeight := -1
-	var topTileOnStack *HiveTile = nil
-
-	for i, tile := range game.Tiles {
-		if tile.Color == game.ColorToMove {
-			if tile.StackHeight > greatestStackHeight {
-				greatestStackHeight = tile.StackHeight
-				topTileOnStack = &game.Tiles[i]
-			}
-		}
-	}
+	fromHeight := game.heightAt(from, mover)
+	toHeight := game.heightAt(to, mover)
+	clockwiseHeight := game.heightAt(clockwise, mover)
+	antiClockwiseHeight := game.heightAt(antiClockwise, mover)
 
-	if topTileOnStack == nil {
-		return validMoves
+	if fromHeight == 0 && toHeight == 0 {
+		return (clockwiseHeight > 0) != (antiClockwiseHeight > 0)
 	}
 
-	for _, adj := range from.AdjacentVectors() {
-		isOccupied := false
+	return min(clockwiseHeight, antiClockwiseHeight) <= max(fromHeight, toHeight)
+}
 
-		for _, tile := range game.Tiles {
-			if tile.Position == adj {
-				isOccupied = true
-				break
-			}
-		}
+// heightAt counts the tiles stacked at a position, leaving out the tile that is being moved
+func (game *HiveGame) heightAt(position HexVectorInt, mover *HiveTile) int {
+	height := 0
 
-		if isOccupied {
-			validMoves[adj] = true
+	for i, tile := range game.Tiles {
+		if tile.Position == position && &game.Tiles[i] != mover {
+			height++
 		}
 	}
 
-	validStackPositions := make([]HexVectorInt, 0, len(validMoves))
-
-	for move := range validMoves {
-		validStackPositions = append(validStackPositions, move)
-	}
-
-	for _, move := range validStackPositions {
-		validMoves[Rotate60().Transform(move.Subtract(from)).Add(from)] = true
-		validMoves[Rotate300().Transform(move.Subtract(from)).Add(from)] = true
-	}
-
-	return validMoves
+	return height
 }
 
 func (game *HiveGame) nextStackHeight(position HexVectorInt) int {
@@ -800,47 +699,36 @@ func (game *HiveGame) nextStackHeight(position HexVectorInt) int {
 	return greatestStackHeight + 1
 }
 
-func (game *HiveGame) mosquitoMoves(from HexVectorInt) map[HexVectorInt]bool {
+func (game *HiveGame) mosquitoMoves(from HexVectorInt) iter.Seq[HexVectorInt] {
 	if game.tileAt(from).StackHeight > 0 {
 		return game.beetleMoves(from)
 	}
 
-	neighbourTiles := make([]HiveTile, 0, 6)
+	return func(yield func(HexVectorInt) bool) {
+		// a mosquito moves as any piece it touches, and the same position can be reached as more
+		// than one of them
+		var borrowed [PieceTypeMosquito + 1]bool
+		yielded := make(map[HexVectorInt]bool)
 
-	for _, adj := range from.AdjacentVectors() {
-		if tile := game.tileAt(adj); tile != nil {
-			neighbourTiles = append(neighbourTiles, *tile)
-		}
-	}
+		for _, adj := range from.AdjacentVectors() {
+			tile := game.tileAt(adj)
+			if tile == nil || tile.PieceType == PieceTypeMosquito || borrowed[tile.PieceType] {
+				continue
+			}
 
-	validMoves := make(map[HexVectorInt]bool)
+			borrowed[tile.PieceType] = true
 
-	addAll := func(dest, src map[HexVectorInt]bool) {
-		for k, v := range src {
-			dest[k] = v
-		}
-	}
+			for move := range game.pieceMoves(tile.PieceType, from) {
+				if yielded[move] {
+					continue
+				}
 
-	for _, tile := range neighbourTiles {
-		switch tile.PieceType {
-		case PieceTypeQueenBee:
-			addAll(validMoves, game.queenBeeMoves(from))
-		case PieceTypeSoldierAnt:
-			addAll(validMoves, game.soldierAntMoves(from))
-		case PieceTypeGrasshopper:
-			addAll(validMoves, game.grasshopperMoves(from))
-		case PieceTypeSpider:
-			addAll(validMoves, game.spiderMoves(from))
-		case PieceTypeBeetle:
-			addAll(validMoves, game.beetleMoves(from))
-		case PieceTypeLadybug:
-			addAll(validMoves, game.ladybugMoves(from))
-		case PieceTypeMosquito:
-			continue
-		default:
-			panic("unhandled case")
+				yielded[move] = true
+
+				if !yield(move) {
+					return
+				}
+			}
 		}
 	}
-
-	return validMoves
 }
@@ -688,29 +688,39 @@ func TestPinQueenAndSurround(t *testing.T) {
 		t.Fatalf("Game being declared as over too early")
 	}
 
+	if !game.MustPass() {
+		t.Fatalf("White has no available moves, so must pass")
+	}
+
+	expectLegal(game.Pass())
+
 	if game.ColorToMove != ColorBlack || game.Move != 4 {
-		t.Fatalf("Did not skip white's move, though white had no available moves")
+		t.Fatalf("Did not pass white's move")
 	}
 
 	expectLegal(game.PlaceTile(HexVectorInt{1, -1}, PieceTypeGrasshopper))
+	expectLegal(game.Pass())
 	if game.ColorToMove != ColorBlack {
 		t.Error("Expected ColorToMove to be black")
 	}
 	expectLegal(game.PlaceTile(HexVectorInt{-1, -1}, PieceTypeGrasshopper))
+	expectLegal(game.Pass())
 	if game.ColorToMove != ColorBlack {
 		t.Error("Expected ColorToMove to be black")
 	}
 	expectLegal(game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeGrasshopper))
+	expectLegal(game.Pass())
 	if game.ColorToMove != ColorBlack {
 		t.Error("Expected ColorToMove to be black")
 	}
 	expectLegal(game.PlaceTile(HexVectorInt{0, -2}, PieceTypeSoldierAnt))
+	expectLegal(game.Pass())
 	if game.ColorToMove != ColorBlack {
 		t.Error("Expected ColorToMove to be black")
 	}
 	expectLegal(game.PlaceTile(HexVectorInt{1, -2}, PieceTypeSoldierAnt))
-	if game.ColorToMove != ColorBlack {
-		t.Error("Expected ColorToMove to be black")
+	if game.MustPass() {
+		t.Error("White cannot be made to pass once the game is over")
 	}
 
 	if over, winner := game.IsOver(); !over {
@@ -720,6 +730,25 @@ func TestPinQueenAndSurround(t *testing.T) {
 	}
 }
 
+func TestCannotPassWithLegalMoves(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+
+	if game.MustPass() {
+		t.Fatalf("Black has legal moves, so need not pass")
+	}
+
+	if game.Pass() {
+		t.Fatalf("Allowed black to pass while having legal moves")
+	}
+
+	if !game.PlayMove(Movement(HexVectorInt{0, 0}, HexVectorInt{0, -1})) {
+		t.Fatalf("Did not allow a legal move through PlayMove")
+	}
+}
+
 func TestGetLegalPlacementsBasic(t *testing.T) {
 	game := CreateHiveGame()
 	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
@@ -752,3 +781,158 @@ func TestGetLegalPlacementsBasic(t *testing.T) {
 		}
 	}
 }
+
+func TestClimbingReferencePositions(t *testing.T) {
+	type reference struct {
+		name    string
+		tiles   []HiveTile
+		from    HexVectorInt
+		legal   []HexVectorInt
+		illegal []HexVectorInt
+	}
+
+	references := []reference{
+		{
+			name: "beetle cannot slide through a gate on the ground",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeBeetle},
+				{Color: ColorBlack, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{0, -1}, PieceType: PieceTypeSpider},
+				{Color: ColorBlack, Position: HexVectorInt{-1, 0}, PieceType: PieceTypeSoldierAnt},
+				{Color: ColorWhite, Position: HexVectorInt{-1, 1}, PieceType: PieceTypeSoldierAnt},
+				{Color: ColorWhite, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+			},
+			from:    HexVectorInt{0, 0},
+			legal:   []HexVectorInt{{0, 1}, {1, -1}, {-1, 0}},
+			illegal: []HexVectorInt{{1, 0}},
+		},
+		{
+			name: "beetle can come down from a stack past lower neighbours",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeSpider},
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorWhite, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+			},
+			from:  HexVectorInt{0, 0},
+			legal: []HexVectorInt{{1, 0}, {1, -1}, {0, 1}, {-1, 1}, {0, -1}},
+		},
+		{
+			name: "beetle cannot come down from a stack through a higher gate",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeSpider},
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorWhite, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{1, -1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+			},
+			from:    HexVectorInt{0, 0},
+			legal:   []HexVectorInt{{1, -1}, {0, 1}, {-1, 1}, {0, -1}},
+			illegal: []HexVectorInt{{1, 0}},
+		},
+		{
+			name: "beetle cannot climb through a higher gate",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeBeetle},
+				{Color: ColorWhite, Position: HexVectorInt{1, 0}, PieceType: PieceTypeSoldierAnt},
+				{Color: ColorWhite, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{1, -1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+			},
+			from:    HexVectorInt{0, 0},
+			legal:   []HexVectorInt{{1, -1}, {0, 1}, {-1, 1}, {0, -1}},
+			illegal: []HexVectorInt{{1, 0}},
+		},
+		{
+			name: "mosquito on a stack is held back by the same gates as a beetle",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeSpider},
+				{Color: ColorWhite, Position: HexVectorInt{0, 0}, PieceType: PieceTypeMosquito, StackHeight: 1},
+				{Color: ColorWhite, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{1, -1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+			},
+			from:    HexVectorInt{0, 0},
+			legal:   []HexVectorInt{{1, -1}, {0, 1}},
+			illegal: []HexVectorInt{{1, 0}},
+		},
+		{
+			name: "ladybug cannot descend through a higher gate",
+			tiles: []HiveTile{
+				{Color: ColorWhite, Position: HexVectorInt{3, 0}, PieceType: PieceTypeLadybug},
+				{Color: ColorBlack, Position: HexVectorInt{2, 0}, PieceType: PieceTypeSoldierAnt},
+				{Color: ColorWhite, Position: HexVectorInt{1, 0}, PieceType: PieceTypeSpider},
+				{Color: ColorWhite, Position: HexVectorInt{1, -1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorBlack, Position: HexVectorInt{1, -1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+				{Color: ColorBlack, Position: HexVectorInt{0, 1}, PieceType: PieceTypeQueenBee},
+				{Color: ColorWhite, Position: HexVectorInt{0, 1}, PieceType: PieceTypeBeetle, StackHeight: 1},
+			},
+			from:    HexVectorInt{3, 0},
+			legal:   []HexVectorInt{{2, -1}, {1, 1}},
+			illegal: []HexVectorInt{{0, 0}},
+		},
+	}
+
+	for _, ref := range references {
+		position := func() HiveGame {
+			game := CreateHiveGame()
+			game.ColorToMove = ColorWhite
+			game.Move = 10
+			game.Tiles = make([]HiveTile, len(ref.tiles))
+			copy(game.Tiles, ref.tiles)
+			return game
+		}
+
+		for _, to := range ref.legal {
+			game := position()
+			if !game.MoveTile(ref.from, to) {
+				t.Errorf("%s: did not allow legal move from %v to %v", ref.name, ref.from, to)
+			}
+		}
+
+		for _, to := range ref.illegal {
+			game := position()
+			if game.MoveTile(ref.from, to) {
+				t.Errorf("%s: allowed illegal move from %v to %v", ref.name, ref.from, to)
+			}
+		}
+	}
+}
+
+func TestCannotMoveBeforeQueenPlaced(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeSoldierAnt)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, 0}, PieceTypeGrasshopper)
+	game.PlaceTile(HexVectorInt{-2, 0}, PieceTypeSoldierAnt)
+
+	if moves := game.LegalMoves(HexVectorInt{1, 0}); len(moves) != 0 {
+		t.Fatalf("Black has not placed their queen, yet has legal moves %v", moves)
+	}
+
+	if game.MoveTile(HexVectorInt{1, 0}, HexVectorInt{-2, 1}) {
+		t.Fatalf("Allowed black to move a tile before placing their queen")
+	}
+
+	for _, move := range game.AllLegalMoves() {
+		if move.MoveType != MoveTypePlacement {
+			t.Fatalf("Black has not placed their queen, yet can make move %+v", move)
+		}
+	}
+
+	if !game.PlaceTile(HexVectorInt{2, 0}, PieceTypeQueenBee) {
+		t.Fatalf("Did not allow black to place their queen")
+	}
+
+	if !game.MoveTile(HexVectorInt{-2, 0}, HexVectorInt{-2, 1}) {
+		t.Fatalf("Did not allow white to move once their queen is placed")
+	}
+
+	if !game.MoveTile(HexVectorInt{2, 0}, HexVectorInt{1, 1}) {
+		t.Fatalf("Did not allow black to move once their queen is placed")
+	}
+}
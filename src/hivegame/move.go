@@ -0,0 +1,76 @@
+package hivegame
+
+import "slices"
+
+type HiveMoveType = int
+
+const (
+	MoveTypePlacement HiveMoveType = 0
+	MoveTypeMovement               = 1
+	MoveTypePass                   = 2
+)
+
+// HiveMove is a single turn of the game. A placement puts a tile of PieceType at To, a movement
+// takes the top tile at From to To, and a pass is made when neither is possible.
+type HiveMove struct {
+	MoveType  HiveMoveType  `json:"moveType"`
+	PieceType HivePieceType `json:"pieceType"`
+	From      HexVectorInt  `json:"from"`
+	To        HexVectorInt  `json:"to"`
+}
+
+func Placement(pieceType HivePieceType, position HexVectorInt) HiveMove {
+	return HiveMove{MoveType: MoveTypePlacement, PieceType: pieceType, To: position}
+}
+
+func Movement(from, to HexVectorInt) HiveMove {
+	return HiveMove{MoveType: MoveTypeMovement, From: from, To: to}
+}
+
+func Pass() HiveMove {
+	return HiveMove{MoveType: MoveTypePass}
+}
+
+// PlayMove returns true if the move was legal
+func (game *HiveGame) PlayMove(move HiveMove) bool {
+	switch move.MoveType {
+	case MoveTypePlacement:
+		return game.PlaceTile(move.To, move.PieceType)
+	case MoveTypeMovement:
+		return game.MoveTile(move.From, move.To)
+	case MoveTypePass:
+		return game.Pass()
+	}
+
+	return false
+}
+
+// transformed maps the positions of the move through a symmetry; placements keep their piece type
+func (move HiveMove) transformed(position func(HexVectorInt) HexVectorInt) HiveMove {
+	if move.MoveType == MoveTypePass {
+		return move
+	}
+
+	if move.MoveType == MoveTypeMovement {
+		move.From = position(move.From)
+	}
+
+	move.To = position(move.To)
+
+	return move
+}
+
+func compareMoves(a, b HiveMove) int {
+	keysA := [6]int{a.MoveType, a.PieceType, a.From.R, a.From.Q, a.To.R, a.To.Q}
+	keysB := [6]int{b.MoveType, b.PieceType, b.From.R, b.From.Q, b.To.R, b.To.Q}
+
+	return slices.Compare(keysA[:], keysB[:])
+}
+
+// AllLegalMoves lists every placement and movement available to the colour to move
+func (game *HiveGame) AllLegalMoves() []HiveMove {
+	moves := slices.AppendSeq(make([]HiveMove, 0), game.AllLegalMovesSeq())
+	slices.SortFunc(moves, compareMoves)
+
+	return moves
+}
@@ -0,0 +1,189 @@
+package hivegame
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// DefaultBookPly is how many moves into each game a book built by AddGame will record
+const DefaultBookPly = 12
+
+// BookMove is a candidate reply stored in an OpeningBook. Wins, draws and losses are counted from
+// the point of view of the player making the move.
+type BookMove struct {
+	Move   HiveMove `json:"move"`
+	Weight int      `json:"weight"`
+	Wins   int      `json:"wins"`
+	Draws  int      `json:"draws"`
+	Losses int      `json:"losses"`
+}
+
+// OpeningBook maps canonical positions to the moves played from them. The moves are stored in the
+// coordinates of the canonical form, so whatever is learned from one game applies to every
+// rotation, reflection and translation of it.
+type OpeningBook struct {
+	// MaxPly is the number of moves from the start of each game that AddGame records
+	MaxPly    int
+	positions map[uint64][]BookMove
+}
+
+// bookLine is the on-disk format of a book: one JSON object per line, per position
+type bookLine struct {
+	Position string     `json:"position"`
+	Moves    []BookMove `json:"moves"`
+}
+
+func NewOpeningBook() *OpeningBook {
+	return &OpeningBook{
+		MaxPly:    DefaultBookPly,
+		positions: make(map[uint64][]BookMove),
+	}
+}
+
+func LoadOpeningBook(reader io.Reader) (*OpeningBook, error) {
+	book := NewOpeningBook()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var line bookLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("opening book line %d: %w", lineNumber, err)
+		}
+
+		position, err := strconv.ParseUint(line.Position, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("opening book line %d: bad position %q", lineNumber, line.Position)
+		}
+
+		book.positions[position] = append(book.positions[position], line.Moves...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+func (book *OpeningBook) Save(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+
+	for _, position := range slices.Sorted(maps.Keys(book.positions)) {
+		err := encoder.Encode(bookLine{
+			Position: strconv.FormatUint(position, 16),
+			Moves:    book.positions[position],
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddGame replays a finished game from the start and records its first MaxPly moves along with
+// the result. An error is returned if the game contains an illegal move, in which case the moves
+// before it have still been recorded.
+func (book *OpeningBook) AddGame(moves []HiveMove, winner HiveColor, draw bool) error {
+	game := CreateHiveGame()
+
+	for ply, move := range moves {
+		if ply >= book.MaxPly {
+			break
+		}
+
+		position := game.CanonicalHash()
+		canonicalMove := game.canonicalMove(move)
+		mover := game.ColorToMove
+
+		if !game.PlayMove(move) {
+			return fmt.Errorf("move %d of the game is illegal", ply+1)
+		}
+
+		book.record(position, canonicalMove, func(bookMove *BookMove) {
+			if draw {
+				bookMove.Draws++
+			} else if winner == mover {
+				bookMove.Wins++
+			} else {
+				bookMove.Losses++
+			}
+		})
+	}
+
+	return nil
+}
+
+func (book *OpeningBook) record(position uint64, move HiveMove, update func(*BookMove)) {
+	moves := book.positions[position]
+
+	for i := range moves {
+		if moves[i].Move == move {
+			moves[i].Weight++
+			update(&moves[i])
+			return
+		}
+	}
+
+	bookMove := BookMove{Move: move, Weight: 1}
+	update(&bookMove)
+	book.positions[position] = append(moves, bookMove)
+}
+
+// BookMoves returns the moves the book knows for this position in the coordinates of this game,
+// the most heavily weighted first
+func (game *HiveGame) BookMoves(book *OpeningBook) []BookMove {
+	stored, ok := book.positions[game.CanonicalHash()]
+
+	if !ok {
+		return make([]BookMove, 0)
+	}
+
+	_, symmetry := game.Canonical()
+
+	moves := make([]BookMove, 0, len(stored))
+	for _, bookMove := range stored {
+		bookMove.Move = bookMove.Move.transformed(symmetry.Invert)
+		moves = append(moves, bookMove)
+	}
+
+	slices.SortStableFunc(moves, func(a, b BookMove) int {
+		return cmp.Compare(b.Weight, a.Weight)
+	})
+
+	return moves
+}
+
+// canonicalMove expresses a move in the coordinates of the canonical form of the game. When the
+// position is symmetric, the smallest of the equivalent moves is chosen so that they are all
+// counted together.
+func (game *HiveGame) canonicalMove(move HiveMove) HiveMove {
+	if len(game.Tiles) == 0 {
+		// every first placement is equivalent
+		move.To = HexVectorInt{}
+		return move
+	}
+
+	_, symmetries := game.canonicalSymmetries()
+
+	best := move.transformed(symmetries[0].Apply)
+	for _, symmetry := range symmetries[1:] {
+		if candidate := move.transformed(symmetry.Apply); compareMoves(candidate, best) < 0 {
+			best = candidate
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,96 @@
+package hivegame
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOpeningBookMergesSymmetricGames(t *testing.T) {
+	book := NewOpeningBook()
+
+	gameA := []HiveMove{
+		Placement(PieceTypeGrasshopper, HexVectorInt{0, 0}),
+		Placement(PieceTypeQueenBee, HexVectorInt{1, 0}),
+		Placement(PieceTypeQueenBee, HexVectorInt{-1, 0}),
+	}
+
+	// gameA rotated by 60 degrees and translated
+	rotated := make([]HiveMove, 0, len(gameA))
+	for _, move := range gameA {
+		rotated = append(rotated, move.transformed(func(v HexVectorInt) HexVectorInt {
+			return Rotate60().Transform(v).Add(HexVectorInt{4, 2})
+		}))
+	}
+
+	if err := book.AddGame(gameA, ColorBlack, false); err != nil {
+		t.Fatalf("Failed to add game: %v", err)
+	}
+	if err := book.AddGame(rotated, ColorWhite, false); err != nil {
+		t.Fatalf("Failed to add rotated game: %v", err)
+	}
+
+	game := CreateHiveGame()
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeGrasshopper)
+	game.PlaceTile(HexVectorInt{0, 1}, PieceTypeQueenBee)
+
+	moves := game.BookMoves(book)
+
+	if len(moves) != 1 {
+		t.Fatalf("Expected the two games to be merged into one book move, got %d", len(moves))
+	}
+
+	if moves[0].Weight != 2 || moves[0].Wins != 1 || moves[0].Losses != 1 {
+		t.Fatalf("Unexpected statistics for book move: %+v", moves[0])
+	}
+
+	if !game.PlayMove(moves[0].Move) {
+		t.Fatalf("Book move %+v is not legal in the position it was looked up from", moves[0].Move)
+	}
+
+	if game.Tiles[2].Position != (HexVectorInt{0, -1}) {
+		t.Fatalf("Book move was not mapped into the coordinates of the game, got %v", game.Tiles[2].Position)
+	}
+}
+
+func TestOpeningBookRejectsIllegalGames(t *testing.T) {
+	book := NewOpeningBook()
+
+	err := book.AddGame([]HiveMove{
+		Placement(PieceTypeQueenBee, HexVectorInt{0, 0}),
+		Placement(PieceTypeQueenBee, HexVectorInt{5, 5}),
+	}, ColorBlack, false)
+
+	if err == nil {
+		t.Fatalf("Expected an illegal game to be rejected")
+	}
+}
+
+func TestOpeningBookSaveAndLoad(t *testing.T) {
+	book := NewOpeningBook()
+
+	err := book.AddGame([]HiveMove{
+		Placement(PieceTypeSpider, HexVectorInt{0, 0}),
+		Placement(PieceTypeSpider, HexVectorInt{1, -1}),
+	}, ColorBlack, true)
+
+	if err != nil {
+		t.Fatalf("Failed to add game: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := book.Save(&buffer); err != nil {
+		t.Fatalf("Failed to save book: %v", err)
+	}
+
+	loaded, err := LoadOpeningBook(&buffer)
+	if err != nil {
+		t.Fatalf("Failed to load book: %v", err)
+	}
+
+	game := CreateHiveGame()
+	moves := game.BookMoves(loaded)
+
+	if len(moves) != 1 || moves[0].Move != Placement(PieceTypeSpider, HexVectorInt{}) || moves[0].Draws != 1 {
+		t.Fatalf("Loaded book does not match what was saved: %+v", moves)
+	}
+}
@@ -0,0 +1,178 @@
+package hivegame
+
+import (
+	"iter"
+	"slices"
+)
+
+// Neighbours yields the six positions adjacent to v
+func (v HexVectorInt) Neighbours() iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		for _, adj := range v.AdjacentVectors() {
+			if !yield(adj) {
+				return
+			}
+		}
+	}
+}
+
+// TilesOfColor yields every tile of the given colour, including those buried beneath a stack
+func (game *HiveGame) TilesOfColor(color HiveColor) iter.Seq[HiveTile] {
+	return func(yield func(HiveTile) bool) {
+		for _, tile := range game.Tiles {
+			if tile.Color == color && !yield(tile) {
+				return
+			}
+		}
+	}
+}
+
+// TilesAt yields the stack of tiles at a position from the bottom up
+func (game *HiveGame) TilesAt(position HexVectorInt) iter.Seq[HiveTile] {
+	return func(yield func(HiveTile) bool) {
+		for height := 0; ; height++ {
+			found := false
+
+			for _, tile := range game.Tiles {
+				if tile.Position == position && tile.StackHeight == height {
+					found = true
+
+					if !yield(tile) {
+						return
+					}
+
+					break
+				}
+			}
+
+			if !found {
+				return
+			}
+		}
+	}
+}
+
+// NeighbouringTiles yields the top tile of each occupied position adjacent to the given one
+func (game *HiveGame) NeighbouringTiles(position HexVectorInt) iter.Seq[HiveTile] {
+	return func(yield func(HiveTile) bool) {
+		for adj := range position.Neighbours() {
+			if tile := game.tileAt(adj); tile != nil && !yield(*tile) {
+				return
+			}
+		}
+	}
+}
+
+// LegalMovesSeq yields the positions the top tile at 'from' can legally move to, as the search for
+// them finds each one
+func (game *HiveGame) LegalMovesSeq(from HexVectorInt) iter.Seq[HexVectorInt] {
+	return game.destinations(from)
+}
+
+// LegalPlacementsSeq yields each position the colour to move may place a tile at, without regard
+// for which tiles are left in its reserve
+func (game *HiveGame) LegalPlacementsSeq() iter.Seq[HexVectorInt] {
+	return func(yield func(HexVectorInt) bool) {
+		if game.Move == 1 && game.ColorToMove == ColorBlack {
+			// The player can play anywhere, but a stupid question (someone calling this function for a
+			// new game) warrants a stupid answer (nothing)
+			return
+		} else if game.Move == 1 {
+			for p := range game.Tiles[0].Position.Neighbours() {
+				if !yield(p) {
+					return
+				}
+			}
+			return
+		}
+
+		seen := make(map[HexVectorInt]bool)
+
+		for tile := range game.TilesOfColor(game.ColorToMove) {
+			for position := range tile.Position.Neighbours() {
+				if seen[position] || game.tileAt(position) != nil {
+					continue
+				}
+
+				seen[position] = true
+
+				neighboursOpponent := false
+				for adjacentTile := range game.NeighbouringTiles(position) {
+					if adjacentTile.Color != game.ColorToMove {
+						neighboursOpponent = true
+						break
+					}
+				}
+
+				if !neighboursOpponent && !yield(position) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// AllLegalMovesSeq yields every placement and movement available to the colour to move, in no
+// particular order, or a pass if there are none
+func (game *HiveGame) AllLegalMovesSeq() iter.Seq[HiveMove] {
+	return func(yield func(HiveMove) bool) {
+		found := false
+
+		for move := range game.legalTurnsSeq() {
+			found = true
+
+			if !yield(move) {
+				return
+			}
+		}
+
+		if !found && game.MustPass() {
+			yield(Pass())
+		}
+	}
+}
+
+// legalTurnsSeq is AllLegalMovesSeq without the pass
+func (game *HiveGame) legalTurnsSeq() iter.Seq[HiveMove] {
+	return func(yield func(HiveMove) bool) {
+		reserve := game.BlackReserve
+		if game.ColorToMove == ColorWhite {
+			reserve = game.WhiteReserve
+		}
+
+		placements := game.LegalPlacementsSeq()
+		if len(game.Tiles) == 0 {
+			// anywhere is as good as anywhere else on an empty board
+			placements = slices.Values([]HexVectorInt{{}})
+		}
+
+		mustPlaceQueen := game.Move == 4 && !game.queenPlaced(game.ColorToMove)
+
+		for position := range placements {
+			for pieceType := PieceTypeQueenBee; pieceType <= PieceTypeMosquito; pieceType++ {
+				if reserve[pieceType] == 0 || (mustPlaceQueen && pieceType != PieceTypeQueenBee) {
+					continue
+				}
+
+				if !yield(Placement(pieceType, position)) {
+					return
+				}
+			}
+		}
+
+		seen := make(map[HexVectorInt]bool)
+		for tile := range game.TilesOfColor(game.ColorToMove) {
+			if seen[tile.Position] {
+				continue
+			}
+
+			seen[tile.Position] = true
+
+			for to := range game.LegalMovesSeq(tile.Position) {
+				if !yield(Movement(tile.Position, to)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package hivegame
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTilesAtYieldsStackFromBottomUp(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, 0}, PieceTypeBeetle)
+	game.PlaceTile(HexVectorInt{-2, 0}, PieceTypeBeetle)
+	game.MoveTile(HexVectorInt{1, 0}, HexVectorInt{0, 0})
+	game.MoveTile(HexVectorInt{-2, 0}, HexVectorInt{-1, 0})
+	game.MoveTile(HexVectorInt{0, 0}, HexVectorInt{-1, 0})
+
+	stack := slices.Collect(game.TilesAt(HexVectorInt{-1, 0}))
+
+	if len(stack) != 3 {
+		t.Fatalf("Expected a stack of 3 tiles, got %d", len(stack))
+	}
+
+	expected := []struct {
+		color     HiveColor
+		pieceType HivePieceType
+	}{
+		{ColorWhite, PieceTypeQueenBee},
+		{ColorWhite, PieceTypeBeetle},
+		{ColorBlack, PieceTypeBeetle},
+	}
+
+	for i, tile := range stack {
+		if tile.StackHeight != i || tile.Color != expected[i].color || tile.PieceType != expected[i].pieceType {
+			t.Fatalf("Unexpected tile at height %d: %+v", i, tile)
+		}
+	}
+}
+
+func TestSequencesMatchSlices(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, 0}, PieceTypeSoldierAnt)
+	game.PlaceTile(HexVectorInt{-2, 0}, PieceTypeSoldierAnt)
+
+	placements := slices.Collect(game.LegalPlacementsSeq())
+	if len(placements) != len(game.LegalPlacements()) {
+		t.Fatalf("LegalPlacementsSeq yielded %d positions, expected %d", len(placements), len(game.LegalPlacements()))
+	}
+
+	moves := slices.Collect(game.LegalMovesSeq(HexVectorInt{1, 0}))
+	if len(moves) != len(game.LegalMoves(HexVectorInt{1, 0})) {
+		t.Fatalf("LegalMovesSeq yielded %d positions, expected %d", len(moves), len(game.LegalMoves(HexVectorInt{1, 0})))
+	}
+
+	blackTiles := slices.Collect(game.TilesOfColor(ColorBlack))
+	if len(blackTiles) != 2 {
+		t.Fatalf("Expected 2 black tiles, got %d", len(blackTiles))
+	}
+
+	neighbours := slices.Collect(game.NeighbouringTiles(HexVectorInt{0, 0}))
+	if len(neighbours) != 2 {
+		t.Fatalf("Expected 2 neighbouring tiles, got %d", len(neighbours))
+	}
+}
+
+func TestAllLegalMovesSeqStopsEarly(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+
+	count := 0
+	for range game.AllLegalMovesSeq() {
+		count++
+		break
+	}
+
+	if count != 1 {
+		t.Fatalf("Expected to stop after the first move")
+	}
+
+	if len(game.AllLegalMoves()) == 0 {
+		t.Fatalf("Expected black to have legal moves")
+	}
+}
+
+func TestLegalMovesSeqStopsEarly(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, 0}, PieceTypeSoldierAnt)
+	game.PlaceTile(HexVectorInt{-2, 0}, PieceTypeSpider)
+	game.PlaceTile(HexVectorInt{1, -1}, PieceTypeGrasshopper)
+	game.PlaceTile(HexVectorInt{-3, 0}, PieceTypeMosquito)
+	game.PlaceTile(HexVectorInt{2, -1}, PieceTypeLadybug)
+	game.PlaceTile(HexVectorInt{-2, -1}, PieceTypeBeetle)
+	game.PlaceTile(HexVectorInt{2, 0}, PieceTypeMosquito)
+
+	for _, color := range []HiveColor{ColorWhite, ColorBlack} {
+		game.ColorToMove = color
+
+		for tile := range game.TilesOfColor(color) {
+			all := slices.Collect(game.LegalMovesSeq(tile.Position))
+
+			seen := make(map[HexVectorInt]bool)
+			for _, move := range all {
+				if seen[move] {
+					t.Fatalf("%+v yielded %v twice", tile, move)
+				}
+				seen[move] = true
+			}
+
+			// the range would panic if the sequence kept yielding after the break
+			count := 0
+			for range game.LegalMovesSeq(tile.Position) {
+				count++
+				break
+			}
+
+			if count != min(len(all), 1) {
+				t.Fatalf("Expected %+v to stop after its first move", tile)
+			}
+		}
+	}
+}
+
+func TestSpiderMovesExactlyThreeSpaces(t *testing.T) {
+	game := CreateHiveGame()
+
+	game.PlaceTile(HexVectorInt{0, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{-1, 0}, PieceTypeQueenBee)
+	game.PlaceTile(HexVectorInt{1, 0}, PieceTypeSpider)
+	game.PlaceTile(HexVectorInt{-2, 0}, PieceTypeSoldierAnt)
+
+	moves := game.LegalMoves(HexVectorInt{1, 0})
+	slices.SortFunc(moves, func(a, b HexVectorInt) int {
+		return slices.Compare([]int{a.Q, a.R}, []int{b.Q, b.R})
+	})
+
+	expected := []HexVectorInt{{-2, 1}, {-1, -1}}
+
+	if !slices.Equal(moves, expected) {
+		t.Fatalf("Expected the spider to reach %v, got %v", expected, moves)
+	}
+}
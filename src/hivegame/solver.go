@@ -0,0 +1,145 @@
+package hivegame
+
+import (
+	"cmp"
+	"slices"
+)
+
+type surroundSolver struct {
+	attacker HiveColor
+	// positions from which the attacker is known not to be able to force a win in the given number
+	// of moves
+	refuted map[solverKey]bool
+}
+
+type solverKey struct {
+	position uint64
+	moves    int
+	// turn is the move number up to the one by which the queen must be placed, as the same
+	// position has different legal moves before then
+	turn int
+}
+
+// SolveSurround searches for a forced win for the colour to move, in which it surrounds the
+// opponent's queen within maxMoves of its own moves whatever the opponent replies. The shortest
+// such win is returned as its principal variation: the winning side's moves alternating with the
+// opponent's most stubborn replies, ending with the surrounding move.
+func (game *HiveGame) SolveSurround(maxMoves int) ([]HiveMove, bool) {
+	if over, _ := game.IsOver(); over {
+		return nil, false
+	}
+
+	solver := surroundSolver{
+		attacker: game.ColorToMove,
+		refuted:  make(map[solverKey]bool),
+	}
+
+	for moves := 1; moves <= maxMoves; moves++ {
+		if line, ok := solver.win(game, moves); ok {
+			return line, true
+		}
+	}
+
+	return nil, false
+}
+
+// win looks for an attacking move after which every defence loses within the given number of moves
+func (solver *surroundSolver) win(game *HiveGame, moves int) ([]HiveMove, bool) {
+	key := solverKey{position: game.CanonicalHash(), moves: moves, turn: min(game.Move, 5)}
+
+	if solver.refuted[key] {
+		return nil, false
+	}
+
+	for _, move := range solver.ordered(game, game.AllLegalMoves()) {
+		next := game.Clone()
+		next.PlayMove(move)
+
+		if over, winner := next.IsOver(); over {
+			if winner == solver.attacker {
+				return []HiveMove{move}, true
+			}
+
+			continue
+		}
+
+		if moves == 1 {
+			continue
+		}
+
+		if line, ok := solver.defend(&next, moves-1); ok {
+			return append([]HiveMove{move}, line...), true
+		}
+	}
+
+	solver.refuted[key] = true
+
+	return nil, false
+}
+
+// defend returns the longest losing line for the defender, or false if it has a reply that holds
+// out for longer than the given number of moves
+func (solver *surroundSolver) defend(game *HiveGame, moves int) ([]HiveMove, bool) {
+	var longest []HiveMove
+
+	for _, reply := range game.AllLegalMoves() {
+		next := game.Clone()
+		next.PlayMove(reply)
+
+		line := []HiveMove{reply}
+
+		if over, winner := next.IsOver(); over {
+			if winner != solver.attacker {
+				return nil, false
+			}
+		} else if rest, ok := solver.win(&next, moves); ok {
+			line = append(line, rest...)
+		} else {
+			return nil, false
+		}
+
+		if len(line) > len(longest) {
+			longest = line
+		}
+	}
+
+	if longest == nil {
+		// a player without moves can always pass, so this only happens once the game is over
+		return nil, false
+	}
+
+	return longest, true
+}
+
+// ordered tries the moves that land next to the defender's queen first, as they are the most
+// likely to lead to a surround
+func (solver *surroundSolver) ordered(game *HiveGame, moves []HiveMove) []HiveMove {
+	var queen *HiveTile
+
+	for i, tile := range game.Tiles {
+		if tile.Color != solver.attacker && tile.PieceType == PieceTypeQueenBee {
+			queen = &game.Tiles[i]
+			break
+		}
+	}
+
+	if queen == nil {
+		return moves
+	}
+
+	touchesQueen := func(move HiveMove) int {
+		for _, adj := range queen.Position.AdjacentVectors() {
+			if adj == move.To {
+				return 1
+			}
+		}
+
+		return 0
+	}
+
+	slices.SortStableFunc(moves, func(a, b HiveMove) int {
+		return cmp.Compare(touchesQueen(b), touchesQueen(a))
+	})
+
+	return moves
+}
@@ -0,0 +1,157 @@
+package hivegame
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// testPuzzle is a line of testdata/puzzles.jsonl, which is also a puzzle file for hivepuzzle
+type testPuzzle struct {
+	Name  string   `json:"name"`
+	Moves int      `json:"moves"`
+	Game  HiveGame `json:"game"`
+}
+
+func loadPuzzle(t *testing.T, name string) testPuzzle {
+	file, err := os.Open("testdata/puzzles.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var puzzle testPuzzle
+
+		if err = json.Unmarshal(scanner.Bytes(), &puzzle); err != nil {
+			t.Fatal(err)
+		}
+
+		if puzzle.Name == name {
+			return puzzle
+		}
+	}
+
+	t.Fatalf("No puzzle named %q", name)
+
+	return testPuzzle{}
+}
+
+// checkLine plays out a principal variation, which must end with the side to move surrounding the
+// opposing queen
+func checkLine(t *testing.T, puzzle HiveGame, line []HiveMove) {
+	game := puzzle.Clone()
+	attacker := game.ColorToMove
+
+	for i, move := range line {
+		if over, _ := game.IsOver(); over {
+			t.Fatalf("Game was over before move %d of %+v", i, line)
+		}
+
+		if !game.PlayMove(move) {
+			t.Fatalf("Move %d of %+v is not legal", i, line)
+		}
+	}
+
+	if over, winner := game.IsOver(); !over || winner != attacker {
+		t.Fatalf("Principal variation %+v does not win the game", line)
+	}
+}
+
+func TestSolveSurroundInOne(t *testing.T) {
+	puzzle := loadPuzzle(t, "ant to finish")
+
+	line, ok := puzzle.Game.SolveSurround(puzzle.Moves)
+
+	if !ok {
+		t.Fatalf("Failed to find the surround in one move")
+	}
+
+	if len(line) != 1 || line[0] != Movement(HexVectorInt{1, 1}, HexVectorInt{0, 1}) {
+		t.Fatalf("Unexpected principal variation %+v", line)
+	}
+
+	checkLine(t, puzzle.Game, line)
+}
+
+func TestSolveSurroundReportsNoForcedWin(t *testing.T) {
+	puzzle := loadPuzzle(t, "ant to finish")
+	puzzle.Game.ColorToMove = ColorWhite
+
+	if _, ok := puzzle.Game.SolveSurround(1); ok {
+		t.Fatalf("Found a win for white where there is none")
+	}
+}
+
+func TestSolveSurroundThroughForcedPass(t *testing.T) {
+	puzzle := loadPuzzle(t, "white must pass")
+
+	if _, ok := puzzle.Game.SolveSurround(1); ok {
+		t.Fatalf("Found a surround in one where there is none")
+	}
+
+	line, ok := puzzle.Game.SolveSurround(puzzle.Moves)
+
+	if !ok {
+		t.Fatalf("Failed to find the surround in two moves")
+	}
+
+	expected := []HiveMove{
+		Movement(HexVectorInt{1, 1}, HexVectorInt{0, 1}),
+		Pass(),
+		Movement(HexVectorInt{-2, 1}, HexVectorInt{-1, 0}),
+	}
+
+	if len(line) != len(expected) {
+		t.Fatalf("Unexpected principal variation %+v", line)
+	}
+
+	for i := range expected {
+		if line[i] != expected[i] {
+			t.Fatalf("Unexpected principal variation %+v", line)
+		}
+	}
+
+	checkLine(t, puzzle.Game, line)
+}
+
+func TestSolveSurroundAgainstEveryDefence(t *testing.T) {
+	puzzle := loadPuzzle(t, "grasshopper defends")
+
+	line, ok := puzzle.Game.SolveSurround(puzzle.Moves)
+
+	if !ok {
+		t.Fatalf("Failed to find the surround in two moves")
+	}
+
+	// the defender has replies other than passing, and the longest of them is the one shown
+	if len(line) != 3 || line[1].MoveType != MoveTypeMovement {
+		t.Fatalf("Unexpected principal variation %+v", line)
+	}
+
+	checkLine(t, puzzle.Game, line)
+
+	// every reply to the first move has to lose, not just the one in the line
+	game := puzzle.Game.Clone()
+	game.PlayMove(line[0])
+
+	for _, reply := range game.AllLegalMoves() {
+		next := game.Clone()
+		next.PlayMove(reply)
+
+		if _, ok = next.SolveSurround(1); !ok {
+			t.Fatalf("Reply %+v holds out against %+v", reply, line[0])
+		}
+	}
+}
+
+func TestSolveSurroundWhenDefenderEscapes(t *testing.T) {
+	puzzle := loadPuzzle(t, "grasshopper steps aside")
+
+	if line, ok := puzzle.Game.SolveSurround(puzzle.Moves); ok {
+		t.Fatalf("Found a win where the defender escapes: %+v", line)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Puzzle is one line of a puzzle file: the side to move in Game should be able to surround the
+// opposing queen in Moves of its own moves, whatever the defence
+type Puzzle struct {
+	Name  string            `json:"name"`
+	Moves int               `json:"moves"`
+	Game  hivegame.HiveGame `json:"game"`
+}
+
+var pieceTypeStrings = map[hivegame.HivePieceType]string{
+	hivegame.PieceTypeQueenBee:    "QUEEN_BEE",
+	hivegame.PieceTypeSoldierAnt:  "SOLDIER_ANT",
+	hivegame.PieceTypeGrasshopper: "GRASSHOPPER",
+	hivegame.PieceTypeSpider:      "SPIDER",
+	hivegame.PieceTypeBeetle:      "BEETLE",
+	hivegame.PieceTypeLadybug:     "LADYBUG",
+	hivegame.PieceTypeMosquito:    "MOSQUITO",
+}
+
+func formatMove(move hivegame.HiveMove) string {
+	switch move.MoveType {
+	case hivegame.MoveTypePlacement:
+		return fmt.Sprintf("PLACE %s (%d, %d)", pieceTypeStrings[move.PieceType], move.To.Q, move.To.R)
+	case hivegame.MoveTypeMovement:
+		return fmt.Sprintf("MOVE (%d, %d) -> (%d, %d)", move.From.Q, move.From.R, move.To.Q, move.To.R)
+	case hivegame.MoveTypePass:
+		return "PASS"
+	}
+
+	return "?"
+}
+
+// solve searches for the puzzle's forced win, and reports it as a line of output. Returns false if
+// there is none.
+func solve(puzzle Puzzle) (string, bool) {
+	line, ok := puzzle.Game.SolveSurround(puzzle.Moves)
+	if !ok {
+		return fmt.Sprintf("FAIL %s: no forced surround in %d move(s)", puzzle.Name, puzzle.Moves), false
+	}
+
+	moves := make([]string, 0, len(line))
+	winningMoves := 0
+	replay := puzzle.Game.Clone()
+
+	for _, move := range line {
+		if replay.ColorToMove == puzzle.Game.ColorToMove {
+			winningMoves++
+		}
+
+		replay.PlayMove(move)
+		moves = append(moves, formatMove(move))
+	}
+
+	if winningMoves < puzzle.Moves {
+		return fmt.Sprintf("OK %s: shorter win in %d move(s): %s", puzzle.Name, winningMoves, strings.Join(moves, ", ")), true
+	}
+
+	return fmt.Sprintf("OK %s: %s", puzzle.Name, strings.Join(moves, ", ")), true
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s <puzzles.jsonl>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error opening puzzle file:\n%v\n", err)
+	}
+	defer file.Close()
+
+	failed := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var puzzle Puzzle
+		if err := json.Unmarshal(scanner.Bytes(), &puzzle); err != nil {
+			log.Fatalf("Error parsing puzzle on line %d:\n%v\n", lineNumber, err)
+		}
+
+		if puzzle.Name == "" {
+			puzzle.Name = fmt.Sprintf("line %d", lineNumber)
+		}
+
+		report, ok := solve(puzzle)
+		if !ok {
+			failed++
+		}
+
+		fmt.Println(report)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Error reading puzzle file:\n%v\n", err)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// the solver's own tests use the same puzzles
+const puzzleFile = "../hivegame/testdata/puzzles.jsonl"
+
+func loadPuzzle(t *testing.T, name string) Puzzle {
+	file, err := os.Open(puzzleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var puzzle Puzzle
+
+		if err = json.Unmarshal(scanner.Bytes(), &puzzle); err != nil {
+			t.Fatalf("Failed to parse puzzle: %v", err)
+		}
+
+		if puzzle.Name == name {
+			return puzzle
+		}
+	}
+
+	t.Fatalf("No puzzle named %q", name)
+
+	return Puzzle{}
+}
+
+func TestSolvePuzzle(t *testing.T) {
+	tests := []struct{ name, report string }{
+		{"ant to finish", "OK ant to finish: MOVE (1, 1) -> (0, 1)"},
+		{"white must pass", "OK white must pass: MOVE (1, 1) -> (0, 1), PASS, MOVE (-2, 1) -> (-1, 0)"},
+	}
+
+	for _, test := range tests {
+		report, ok := solve(loadPuzzle(t, test.name))
+
+		if !ok || report != test.report {
+			t.Errorf("Unexpected report %q", report)
+		}
+	}
+}
+
+func TestSolveReportsShorterWin(t *testing.T) {
+	puzzle := loadPuzzle(t, "ant to finish")
+	puzzle.Moves = 2
+
+	report, ok := solve(puzzle)
+
+	if !ok || report != "OK ant to finish: shorter win in 1 move(s): MOVE (1, 1) -> (0, 1)" {
+		t.Fatalf("Unexpected report %q", report)
+	}
+}
+
+func TestSolveReportsUnsolvablePuzzle(t *testing.T) {
+	report, ok := solve(loadPuzzle(t, "grasshopper steps aside"))
+
+	if ok || report != "FAIL grasshopper steps aside: no forced surround in 2 move(s)" {
+		t.Fatalf("Unexpected report %q", report)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUsernameTaken = errors.New("username is taken")
+
+type Account struct {
+	// Id is a player id, from the same allocator as guests
+	Id          uint64 `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	// PasswordHash is in the form HashPassword returns
+	PasswordHash string    `json:"passwordHash"`
+	Created      time.Time `json:"created"`
+}
+
+// AccountStore keeps accounts by username, appending each new or changed account to a JSON-lines
+// file if it has one, with the last line for a username taking precedence
+type AccountStore struct {
+	mutex      sync.Mutex
+	byUsername map[string]Account
+	byId       map[uint64]string
+	// nil if accounts only last for the life of the server
+	file *os.File
+}
+
+// OpenAccountStore loads the accounts from the file at path, or keeps them in memory if path is
+// empty
+func OpenAccountStore(path string) (*AccountStore, error) {
+	store := &AccountStore{
+		byUsername: make(map[string]Account),
+		byId:       make(map[uint64]string),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var account Account
+
+		if err = json.Unmarshal(scanner.Bytes(), &account); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		store.byUsername[account.Username] = account
+		store.byId[account.Id] = account.Username
+	}
+
+	if err = scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	store.file = file
+
+	return store, nil
+}
+
+// NormalizeUsername is the form usernames are stored and looked up in, so that two players cannot
+// have names differing only in case
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Create adds an account, so long as its username is not already taken
+func (store *AccountStore) Create(account Account) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.byUsername[account.Username]; ok {
+		return ErrUsernameTaken
+	}
+
+	if err := store.write(account); err != nil {
+		return err
+	}
+
+	store.byUsername[account.Username] = account
+	store.byId[account.Id] = account.Username
+
+	return nil
+}
+
+func (store *AccountStore) ByUsername(username string) (Account, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	account, ok := store.byUsername[username]
+
+	return account, ok
+}
+
+func (store *AccountStore) ById(id uint64) (Account, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	username, ok := store.byId[id]
+	if !ok {
+		return Account{}, false
+	}
+
+	return store.byUsername[username], true
+}
+
+func (store *AccountStore) write(account Account) error {
+	if store.file == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.file.Write(append(line, '\n'))
+
+	return err
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TokenIssuer = "HiveServer"
+	// AccessTokenLifetime is kept short, as a token outlives the revocation of its session by up to
+	// this long in anything that does not check the session. Clients use their refresh token to get
+	// a new one.
+	AccessTokenLifetime = 15 * time.Minute
+	// defaultKeyId names JWT_SECRET when JWT_KEYS is not set
+	defaultKeyId = "default"
+)
+
+// tokenEpoch is when the tokens the server accepts were first issued. Anything issued before it
+// could carry the id of a different player, or have been signed with an old key.
+var tokenEpoch time.Time
+
+// PlayerClaims are the claims of every token the server issues
+type PlayerClaims struct {
+	PlayerId  uint64 `json:"id"`
+	SessionId string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// SetTokenEpoch rejects tokens issued before JWT_EPOCH, given in unix seconds. If player ids are
+// not kept across restarts, tokens from before this start are rejected as well, as their ids will
+// be handed out again.
+func SetTokenEpoch(persistentIds bool) error {
+	tokenEpoch = time.Time{}
+
+	if epoch := os.Getenv("JWT_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		tokenEpoch = time.Unix(seconds, 0)
+	}
+
+	if !persistentIds {
+		// issued at times only have a resolution of a second
+		tokenEpoch = time.Now().Truncate(time.Second)
+	}
+
+	return nil
+}
+
+// signingKeys reads the keys tokens may be signed with from JWT_KEYS, a comma separated list of
+// kid:secret pairs, along with the kid of the one new tokens are signed with from JWT_ACTIVE_KID.
+// Keeping an old key in the list after moving JWT_ACTIVE_KID on lets its tokens run out rather than
+// being cut off. Without JWT_KEYS, JWT_SECRET is the only key.
+func signingKeys() (map[string][]byte, string, error) {
+	keys := make(map[string][]byte)
+
+	list := os.Getenv("JWT_KEYS")
+	if list == "" {
+		if os.Getenv("JWT_SECRET") == "" {
+			return nil, "", errors.New("no signing keys are set")
+		}
+
+		keys[defaultKeyId] = []byte(os.Getenv("JWT_SECRET"))
+
+		return keys, defaultKeyId, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, "", errors.New("JWT_KEYS must be a list of kid:secret pairs")
+		}
+
+		keys[kid] = []byte(secret)
+	}
+
+	active := os.Getenv("JWT_ACTIVE_KID")
+	if _, ok := keys[active]; !ok {
+		return nil, "", errors.New("JWT_ACTIVE_KID must name one of JWT_KEYS")
+	}
+
+	return keys, active, nil
+}
+
+// IssuePlayerToken signs an access token for the player with the given id, on one of their sessions
+func IssuePlayerToken(playerId uint64, sessionId string) (string, error) {
+	keys, active, err := signingKeys()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, PlayerClaims{
+		PlayerId:  playerId,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenLifetime)),
+		},
+	})
+	token.Header["kid"] = active
+
+	return token.SignedString(keys[active])
+}
+
+// ParsePlayerToken checks a token and returns its claims. Only HS256 tokens signed with a current
+// key, from this issuer, that have not expired and were issued since the epoch are accepted.
+func ParsePlayerToken(tokenString string) (*PlayerClaims, error) {
+	keys, _, err := signingKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := new(PlayerClaims)
+
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := keys[kid]
+		if !ok {
+			return nil, errors.New("token was signed with an unknown key")
+		}
+
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.IssuedAt == nil || claims.IssuedAt.Before(tokenEpoch) {
+		return nil, errors.New("token was issued before the current epoch")
+	}
+
+	if claims.PlayerId == 0 || claims.SessionId == "" {
+		return nil, errors.New("token has no player or session id")
+	}
+
+	return claims, nil
+}
+
+// Authenticate is ParsePlayerToken for a token whose session must not have been revoked
+func Authenticate(tokenString string, sessions *SessionStore) (*PlayerClaims, error) {
+	claims, err := ParsePlayerToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sessions.Active(claims.SessionId) {
+		return nil, errors.New("session has been revoked")
+	}
+
+	return claims, nil
+}
+
+// AuthenticateRequest is Authenticate for the bearer token in a request's Authorization header
+func AuthenticateRequest(r *http.Request, sessions *SessionStore) (*PlayerClaims, error) {
+	var givenToken string
+
+	if _, err := fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &givenToken); err != nil {
+		return nil, err
+	}
+
+	return Authenticate(givenToken, sessions)
+}
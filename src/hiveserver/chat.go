@@ -0,0 +1,127 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxChatLength = 200
+	// MaxChatLines is how many lines of chat are kept with a game, after which further lines are
+	// dropped
+	MaxChatLines = 500
+	// a player can send chatBurst messages at once, and then one every chatRefill
+	chatBurst  = 5
+	chatRefill = 2 * time.Second
+	// chat is saved at most once per chatSaveDelay, as the game's whole record is written each time
+	chatSaveDelay = 10 * time.Second
+)
+
+// maskedWords are replaced with asterisks wherever they appear as whole words, whatever their case
+var maskedWords = []string{
+	"arse", "arsehole", "ass", "asshole", "bastard", "bitch", "bollocks", "bullshit", "cock",
+	"crap", "cunt", "damn", "dick", "dickhead", "fuck", "fucked", "fucker", "fucking", "motherfucker",
+	"piss", "prick", "pussy", "shit", "shitty", "slut", "twat", "wanker", "whore",
+}
+
+var maskedPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(maskedWords, "|") + `)\b`)
+
+// ChatMessage is a line of chat from one of the players
+type ChatMessage struct {
+	Color hivegame.HiveColor `json:"color"`
+	Text  string             `json:"text"`
+	Sent  time.Time          `json:"sent"`
+}
+
+// chatLimiter is a token bucket that keeps a player from flooding the chat
+type chatLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if there is one left, after adding those earned since the last message
+func (limiter *chatLimiter) allow(now time.Time) bool {
+	if limiter.last.IsZero() {
+		limiter.tokens = chatBurst
+	} else {
+		limiter.tokens += float64(now.Sub(limiter.last)) / float64(chatRefill)
+		limiter.tokens = min(limiter.tokens, chatBurst)
+	}
+
+	limiter.last = now
+
+	if limiter.tokens < 1 {
+		return false
+	}
+
+	limiter.tokens--
+
+	return true
+}
+
+// maskProfanity replaces each masked word in the text with as many asterisks as it has letters
+func maskProfanity(text string) string {
+	return maskedPattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	})
+}
+
+// Chat relays a line of chat from the player of the given colour to both players and any
+// spectators, and keeps it with the game, which is saved within chatSaveDelay. Chat that is empty,
+// too long, sent too quickly after the player's last few lines or past MaxChatLines is dropped.
+// Players can chat until their connections are closed, so also after the game is over, though that
+// chat is only relayed to them and not kept. The caller must hold the game's lock.
+func (hg *HostedGame) Chat(color hivegame.HiveColor, message *ChatMessage) {
+	if message == nil {
+		return
+	}
+
+	text := strings.TrimSpace(message.Text)
+	if text == "" || !utf8.ValidString(text) || utf8.RuneCountInString(text) > MaxChatLength {
+		return
+	}
+
+	now := time.Now()
+	if len(hg.chat) >= MaxChatLines || !hg.chatLimiters[color].allow(now) {
+		return
+	}
+
+	chat := ChatMessage{
+		Color: color,
+		Text:  maskProfanity(text),
+		Sent:  now,
+	}
+
+	for _, c := range []hivegame.HiveColor{hivegame.ColorBlack, hivegame.ColorWhite} {
+		hg.sendOrLog(c, PlayMessage{
+			Event: EventChat,
+			Chat:  &chat,
+		})
+	}
+
+	// spectators are sent off once they have seen the game finish, and its record has been saved
+	// for the last time
+	if hg.completed {
+		return
+	}
+
+	hg.chat = append(hg.chat, chat)
+
+	hg.broadcast(PlayMessage{
+		Event: EventChat,
+		Chat:  &chat,
+	})
+
+	if hg.chatSaveTimer == nil {
+		hg.chatSaveTimer = time.AfterFunc(chatSaveDelay, func() {
+			hg.condition.L.Lock()
+			defer hg.condition.L.Unlock()
+
+			hg.chatSaveTimer = nil
+			hg.Persist()
+		})
+	}
+}
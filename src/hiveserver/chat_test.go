@@ -0,0 +1,112 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatLimiter(t *testing.T) {
+	var limiter chatLimiter
+	now := time.Now()
+
+	for i := 0; i < chatBurst; i++ {
+		if !limiter.allow(now) {
+			t.Fatalf("Message %d of a burst was refused", i+1)
+		}
+	}
+
+	if limiter.allow(now) {
+		t.Fatalf("A message past the burst was allowed")
+	}
+
+	if limiter.allow(now.Add(chatRefill / 2)) {
+		t.Fatalf("A message was allowed before a token was earned")
+	}
+
+	if !limiter.allow(now.Add(chatRefill)) {
+		t.Fatalf("A message was refused after a token was earned")
+	}
+
+	// tokens stop building up once the bucket is full
+	later := now.Add(100 * chatRefill)
+	for i := 0; i < chatBurst; i++ {
+		limiter.allow(later)
+	}
+
+	if limiter.allow(later) {
+		t.Fatalf("More than a burst was allowed after a long wait")
+	}
+}
+
+func TestMaskProfanity(t *testing.T) {
+	tests := []struct{ text, expected string }{
+		{"good game", "good game"},
+		{"oh shit", "oh ****"},
+		{"DAMN, that was close", "****, that was close"},
+		{"scrap the classic assassin", "scrap the classic assassin"},
+		{"ass-backwards", "***-backwards"},
+	}
+
+	for _, test := range tests {
+		if masked := maskProfanity(test.text); masked != test.expected {
+			t.Errorf("Expected %q to be masked as %q, got %q", test.text, test.expected, masked)
+		}
+	}
+}
+
+func TestChatDropsInvalidLines(t *testing.T) {
+	store := NewMemoryGameStore()
+	game := NewHostedGame("ABC", HostedGameOptions{}, store)
+
+	game.condition.L.Lock()
+	defer game.condition.L.Unlock()
+
+	game.Chat(hivegame.ColorBlack, nil)
+	game.Chat(hivegame.ColorBlack, &ChatMessage{Text: "   "})
+	game.Chat(hivegame.ColorBlack, &ChatMessage{Text: strings.Repeat("a", MaxChatLength+1)})
+	game.Chat(hivegame.ColorBlack, &ChatMessage{Text: "\xff"})
+	game.Chat(hivegame.ColorWhite, &ChatMessage{Text: "  hello  ", Color: hivegame.ColorBlack})
+
+	if len(game.chat) != 1 {
+		t.Fatalf("Expected one line of chat, got %+v", game.chat)
+	}
+
+	if line := game.chat[0]; line.Text != "hello" || line.Color != hivegame.ColorWhite {
+		t.Fatalf("Chat was not kept as sent by white: %+v", line)
+	}
+
+	// chat is saved a while later, rather than line by line
+	if _, saved, _ := store.Load("ABC"); saved {
+		t.Fatalf("Chat was saved straight away")
+	}
+
+	if game.chatSaveTimer == nil {
+		t.Fatalf("No save was scheduled for the chat")
+	}
+
+	game.chatSaveTimer.Stop()
+}
+
+func TestChatKeptOnlyWhileGameIsOn(t *testing.T) {
+	game := NewHostedGame("ABC", HostedGameOptions{}, NewMemoryGameStore())
+
+	game.condition.L.Lock()
+	defer game.condition.L.Unlock()
+
+	game.chat = make([]ChatMessage, MaxChatLines)
+	game.Chat(hivegame.ColorBlack, &ChatMessage{Text: "one too many"})
+
+	if len(game.chat) != MaxChatLines {
+		t.Fatalf("Chat past the limit was kept")
+	}
+
+	game.chat = nil
+	game.completed = true
+	game.Chat(hivegame.ColorBlack, &ChatMessage{Text: "good game"})
+
+	if len(game.chat) != 0 || game.chatSaveTimer != nil {
+		t.Fatalf("Chat after the game was over was kept")
+	}
+}
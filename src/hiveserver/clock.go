@@ -0,0 +1,199 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ClockSuddenDeath gives each player a fixed amount of time for the whole game
+	ClockSuddenDeath = "SUDDEN_DEATH"
+	// ClockFischer adds the increment to a player's clock after each of their moves
+	ClockFischer = "FISCHER"
+	// ClockBronstein gives back the time spent on a move, up to the increment
+	ClockBronstein = "BRONSTEIN"
+	// ClockDelay only starts taking time off a player's clock once the increment has passed
+	ClockDelay = "DELAY"
+)
+
+const ReasonTimeout = "TIMEOUT"
+
+// TimeControl is the clock a hosted game is played with. Increment is the increment for Fischer
+// clocks and the delay for Bronstein and simple delay clocks.
+type TimeControl struct {
+	Kind      string        `json:"kind"`
+	Initial   time.Duration `json:"initial"`
+	Increment time.Duration `json:"increment"`
+}
+
+// ParseTimeControl reads the clock, initial and increment query parameters, with times given in
+// seconds. Returns nil if no clock was asked for.
+func ParseTimeControl(query url.Values) (*TimeControl, error) {
+	if query.Get("clock") == "" {
+		return nil, nil
+	}
+
+	initial, err := strconv.Atoi(query.Get("initial"))
+	if err != nil {
+		return nil, errors.New("initial time must be a number of seconds")
+	}
+
+	var increment int
+	if query.Has("increment") {
+		increment, err = strconv.Atoi(query.Get("increment"))
+		if err != nil {
+			return nil, errors.New("increment must be a number of seconds")
+		}
+	}
+
+	return NewTimeControl(query.Get("clock"), initial, increment)
+}
+
+// NewTimeControl checks a clock as clients name it, with times in seconds. Returns nil if clock is
+// empty.
+func NewTimeControl(clock string, initial, increment int) (*TimeControl, error) {
+	var kind string
+
+	switch clock {
+	case "":
+		return nil, nil
+	case "sudden-death":
+		kind = ClockSuddenDeath
+	case "fischer":
+		kind = ClockFischer
+	case "bronstein":
+		kind = ClockBronstein
+	case "delay":
+		kind = ClockDelay
+	default:
+		return nil, errors.New("unknown clock")
+	}
+
+	if initial <= 0 {
+		return nil, errors.New("initial time must be a positive number of seconds")
+	}
+
+	if kind == ClockSuddenDeath {
+		increment = 0
+	} else if increment < 0 {
+		return nil, errors.New("increment must not be negative")
+	}
+
+	return &TimeControl{
+		Kind:      kind,
+		Initial:   time.Duration(initial) * time.Second,
+		Increment: time.Duration(increment) * time.Second,
+	}, nil
+}
+
+// ClockState is a snapshot of both players' clocks as they are sent to clients
+type ClockState struct {
+	Kind        string `json:"kind"`
+	InitialMs   int64  `json:"initialMs"`
+	IncrementMs int64  `json:"incrementMs"`
+	BlackMs     int64  `json:"blackMs"`
+	WhiteMs     int64  `json:"whiteMs"`
+	// Running is false until both players have joined
+	Running bool `json:"running"`
+}
+
+// GameClock keeps the time left to each player. It holds no lock of its own, and is only used
+// while holding the lock of the game it belongs to.
+type GameClock struct {
+	control TimeControl
+	// time left to each player, indexed by colour, as of the start of the current turn
+	remaining [2]time.Duration
+	running   bool
+	// the colour whose clock is running and when their turn started
+	turn        hivegame.HiveColor
+	turnStarted time.Time
+}
+
+func NewGameClock(control TimeControl) *GameClock {
+	return &GameClock{
+		control:   control,
+		remaining: [2]time.Duration{control.Initial, control.Initial},
+	}
+}
+
+// Restore sets the time left to each player from a snapshot, such as one saved before a restart
+func (clock *GameClock) Restore(state *ClockState) {
+	clock.remaining[hivegame.ColorBlack] = time.Duration(state.BlackMs) * time.Millisecond
+	clock.remaining[hivegame.ColorWhite] = time.Duration(state.WhiteMs) * time.Millisecond
+}
+
+// Start runs the clock of the given colour from now
+func (clock *GameClock) Start(color hivegame.HiveColor, now time.Time) {
+	clock.running = true
+	clock.turn = color
+	clock.turnStarted = now
+}
+
+// Switch ends the turn of the colour whose clock is running and starts their opponent's
+func (clock *GameClock) Switch(now time.Time) {
+	if !clock.running {
+		return
+	}
+
+	elapsed := now.Sub(clock.turnStarted)
+
+	left := clock.left(clock.turn, now)
+	if left > 0 {
+		switch clock.control.Kind {
+		case ClockFischer:
+			left += clock.control.Increment
+		case ClockBronstein:
+			left += min(elapsed, clock.control.Increment)
+		}
+	}
+
+	clock.remaining[clock.turn] = left
+	clock.Start(opponentOf(clock.turn), now)
+}
+
+// Flagged is true if the colour has run out of time
+func (clock *GameClock) Flagged(color hivegame.HiveColor, now time.Time) bool {
+	return clock.left(color, now) <= 0
+}
+
+// UntilFlag is how long the colour whose clock is running has before they run out of time, and
+// the colour in question
+func (clock *GameClock) UntilFlag(now time.Time) (time.Duration, hivegame.HiveColor) {
+	left := clock.left(clock.turn, now)
+
+	if clock.control.Kind == ClockDelay {
+		// the delay still to come runs before the clock does
+		left += max(0, clock.control.Increment-now.Sub(clock.turnStarted))
+	}
+
+	return left, clock.turn
+}
+
+func (clock *GameClock) State(now time.Time) *ClockState {
+	return &ClockState{
+		Kind:        clock.control.Kind,
+		InitialMs:   clock.control.Initial.Milliseconds(),
+		IncrementMs: clock.control.Increment.Milliseconds(),
+		BlackMs:     max(0, clock.left(hivegame.ColorBlack, now)).Milliseconds(),
+		WhiteMs:     max(0, clock.left(hivegame.ColorWhite, now)).Milliseconds(),
+		Running:     clock.running,
+	}
+}
+
+// left is the time the colour has on their clock at the given moment
+func (clock *GameClock) left(color hivegame.HiveColor, now time.Time) time.Duration {
+	if !clock.running || color != clock.turn {
+		return clock.remaining[color]
+	}
+
+	elapsed := now.Sub(clock.turnStarted)
+
+	if clock.control.Kind == ClockDelay {
+		elapsed = max(0, elapsed-clock.control.Increment)
+	}
+
+	return clock.remaining[color] - elapsed
+}
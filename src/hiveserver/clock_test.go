@@ -0,0 +1,130 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// playTurns starts the clock with black to move, then has each player spend the given time in
+// turn, starting with black
+func playTurns(control TimeControl, spent ...time.Duration) (*GameClock, time.Time) {
+	clock := NewGameClock(control)
+	now := time.Unix(0, 0)
+
+	clock.Start(hivegame.ColorBlack, now)
+
+	for _, duration := range spent {
+		now = now.Add(duration)
+		clock.Switch(now)
+	}
+
+	return clock, now
+}
+
+func TestClockKinds(t *testing.T) {
+	tests := []struct {
+		kind         string
+		black, white time.Duration
+	}{
+		// black spends 3s then 10s, white 1s then 20s, with a 5s increment or delay
+		{ClockSuddenDeath, 47 * time.Second, 39 * time.Second},
+		{ClockFischer, 57 * time.Second, 49 * time.Second},
+		{ClockBronstein, 55 * time.Second, 45 * time.Second},
+		{ClockDelay, 55 * time.Second, 45 * time.Second},
+	}
+
+	for _, test := range tests {
+		control := TimeControl{Kind: test.kind, Initial: time.Minute, Increment: 5 * time.Second}
+		if test.kind == ClockSuddenDeath {
+			control.Increment = 0
+		}
+
+		clock, now := playTurns(control, 3*time.Second, time.Second, 10*time.Second, 20*time.Second)
+		state := clock.State(now)
+
+		if state.BlackMs != test.black.Milliseconds() || state.WhiteMs != test.white.Milliseconds() {
+			t.Errorf("%s clock left black %dms and white %dms, expected %v and %v", test.kind, state.BlackMs,
+				state.WhiteMs, test.black, test.white)
+		}
+	}
+}
+
+func TestDelayClockWaitsBeforeRunning(t *testing.T) {
+	clock, now := playTurns(TimeControl{Kind: ClockDelay, Initial: time.Minute, Increment: 5 * time.Second})
+
+	if left := clock.left(hivegame.ColorBlack, now.Add(4*time.Second)); left != time.Minute {
+		t.Fatalf("Delay clock ran during the delay, leaving %v", left)
+	}
+
+	if untilFlag, color := clock.UntilFlag(now); untilFlag != 65*time.Second || color != hivegame.ColorBlack {
+		t.Fatalf("Expected black to flag in 65s, got %v for colour %d", untilFlag, color)
+	}
+}
+
+func TestClockFlagsWithoutIncrement(t *testing.T) {
+	clock, now := playTurns(TimeControl{Kind: ClockFischer, Initial: time.Minute, Increment: 5 * time.Second},
+		61*time.Second)
+
+	if !clock.Flagged(hivegame.ColorBlack, now) {
+		t.Fatalf("Black should have flagged")
+	}
+
+	if state := clock.State(now); state.BlackMs != 0 {
+		t.Fatalf("A flagged player was given the increment, leaving %dms", state.BlackMs)
+	}
+}
+
+func TestClockStoppedUntilStarted(t *testing.T) {
+	clock := NewGameClock(TimeControl{Kind: ClockFischer, Initial: time.Minute})
+	later := time.Now().Add(time.Hour)
+
+	clock.Switch(later)
+
+	if state := clock.State(later); state.Running || state.BlackMs != time.Minute.Milliseconds() {
+		t.Fatalf("Clock ran before it was started: %+v", state)
+	}
+}
+
+func TestClockRestore(t *testing.T) {
+	clock := NewGameClock(TimeControl{Kind: ClockFischer, Initial: time.Minute})
+	clock.Restore(&ClockState{BlackMs: 1500, WhiteMs: 2500})
+
+	state := clock.State(time.Now())
+
+	if state.BlackMs != 1500 || state.WhiteMs != 2500 {
+		t.Fatalf("Clock was not restored: %+v", state)
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected *TimeControl
+		valid    bool
+	}{
+		{"", nil, true},
+		{"clock=fischer&initial=300&increment=2", &TimeControl{ClockFischer, 5 * time.Minute, 2 * time.Second}, true},
+		{"clock=sudden-death&initial=60&increment=5", &TimeControl{ClockSuddenDeath, time.Minute, 0}, true},
+		{"clock=delay&initial=60", &TimeControl{ClockDelay, time.Minute, 0}, true},
+		{"clock=hourglass&initial=60", nil, false},
+		{"clock=fischer", nil, false},
+		{"clock=fischer&initial=0", nil, false},
+		{"clock=bronstein&initial=60&increment=-1", nil, false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		control, err := ParseTimeControl(query)
+
+		if (err == nil) != test.valid {
+			t.Errorf("Query %q: unexpected error %v", test.query, err)
+			continue
+		}
+
+		if !equalTimeControls(control, test.expected) {
+			t.Errorf("Query %q: expected %+v, got %+v", test.query, test.expected, control)
+		}
+	}
+}
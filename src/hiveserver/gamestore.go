@@ -0,0 +1,266 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// GameRecord is everything kept about a hosted game, enough to replay it from the start
+type GameRecord struct {
+	Id          string `json:"id"`
+	BlackPlayer uint64 `json:"blackPlayer"`
+	WhitePlayer uint64 `json:"whitePlayer"`
+	// nil for a game without a clock
+	TimeControl    *TimeControl  `json:"timeControl,omitempty"`
+	SpectatorDelay time.Duration `json:"spectatorDelay"`
+	Public         bool          `json:"public"`
+	// empty for games saved before variants, which were all DefaultVariant
+	Variant     string     `json:"variant,omitempty"`
+	CreatorId   uint64     `json:"creatorId"`
+	CreatorName string     `json:"creatorName"`
+	BlackSeat   uint64     `json:"blackSeat,omitempty"`
+	WhiteSeat   uint64     `json:"whiteSeat,omitempty"`
+	Moves       []HiveMove `json:"moves"`
+	// Chat is every line the players sent, masked as it was relayed
+	Chat []ChatMessage `json:"chat,omitempty"`
+	// the clocks as of the last save, for games with a time control
+	Clock *ClockState `json:"clock,omitempty"`
+	// nil until the game is over
+	Result  *GameResult `json:"result,omitempty"`
+	Created time.Time   `json:"created"`
+	Updated time.Time   `json:"updated"`
+}
+
+type GameResult struct {
+	// nil for a draw
+	Winner *hivegame.HiveColor `json:"winner,omitempty"`
+	Reason string              `json:"reason"`
+}
+
+// GameStore keeps game records beyond the life of the server. Implementations must be safe to use
+// from many goroutines at once.
+type GameStore interface {
+	// Save replaces any record with the same id
+	Save(record GameRecord) error
+	Load(id string) (GameRecord, bool, error)
+	// InProgress lists the records of games without a result
+	InProgress() ([]GameRecord, error)
+}
+
+// NewGameStore opens the file store at GAME_STORE_PATH if it is set, and otherwise keeps games in
+// memory
+func NewGameStore() (GameStore, error) {
+	path := os.Getenv("GAME_STORE_PATH")
+
+	if path == "" {
+		return NewMemoryGameStore(), nil
+	}
+
+	return OpenFileGameStore(path)
+}
+
+type MemoryGameStore struct {
+	mutex   sync.Mutex
+	records map[string]GameRecord
+}
+
+func NewMemoryGameStore() *MemoryGameStore {
+	return &MemoryGameStore{
+		records: make(map[string]GameRecord),
+	}
+}
+
+func (store *MemoryGameStore) Save(record GameRecord) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.records[record.Id] = copyRecord(record)
+
+	return nil
+}
+
+func (store *MemoryGameStore) Load(id string) (GameRecord, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, ok := store.records[id]
+
+	return copyRecord(record), ok, nil
+}
+
+func (store *MemoryGameStore) InProgress() ([]GameRecord, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var records []GameRecord
+
+	for _, record := range store.records {
+		if record.Result == nil {
+			records = append(records, copyRecord(record))
+		}
+	}
+
+	return records, nil
+}
+
+// copyRecord stops the caller and the store from sharing a move list or chat
+func copyRecord(record GameRecord) GameRecord {
+	moves := make([]HiveMove, len(record.Moves))
+	copy(moves, record.Moves)
+	record.Moves = moves
+
+	chat := make([]ChatMessage, len(record.Chat))
+	copy(chat, record.Chat)
+	record.Chat = chat
+
+	return record
+}
+
+// compactionRatio is how many times over its compacted size a game store's file may grow before it
+// is compacted again. Each save writes a game's whole record, so without compacting the file would
+// grow with the square of the number of moves.
+const compactionRatio = 4
+
+// minCompactedSize keeps a store with only a few short games from compacting on every other save
+const minCompactedSize = 64 * 1024
+
+// FileGameStore appends each record to a JSON-lines file as it is saved, with the last line for an
+// id taking precedence. The file is compacted down to one line per game whenever it is opened, and
+// again whenever it grows compactionRatio times over.
+type FileGameStore struct {
+	// its mutex guards the file as well as the records
+	memory *MemoryGameStore
+	path   string
+	file   *os.File
+	// the size of the file, and its size when it was last compacted
+	size          int64
+	compactedSize int64
+}
+
+func OpenFileGameStore(path string) (*FileGameStore, error) {
+	store := &FileGameStore{
+		memory: NewMemoryGameStore(),
+		path:   path,
+	}
+
+	existing, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		// a line holds a whole game's moves
+		scanner.Buffer(nil, 16*1024*1024)
+
+		for scanner.Scan() {
+			var record GameRecord
+
+			if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				_ = existing.Close()
+				return nil, err
+			}
+
+			store.memory.records[record.Id] = record
+		}
+
+		err = scanner.Err()
+		_ = existing.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err = store.compact(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// compact writes the latest record of each game out alongside the old file and swaps them over,
+// so that a crash part way through loses nothing. It must be called while holding the store's
+// mutex, or before the store is shared.
+func (store *FileGameStore) compact() error {
+	compacted, err := os.CreateTemp(filepath.Dir(store.path), filepath.Base(store.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	var size int64
+
+	for _, record := range store.memory.records {
+		var line []byte
+
+		line, err = json.Marshal(record)
+		if err == nil {
+			_, err = compacted.Write(append(line, '\n'))
+		}
+
+		if err != nil {
+			break
+		}
+
+		size += int64(len(line)) + 1
+	}
+
+	if err == nil {
+		err = compacted.Sync()
+	}
+
+	if err == nil {
+		err = os.Rename(compacted.Name(), store.path)
+	}
+
+	if err != nil {
+		_ = compacted.Close()
+		_ = os.Remove(compacted.Name())
+		return err
+	}
+
+	if store.file != nil {
+		_ = store.file.Close()
+	}
+
+	store.file = compacted
+	store.size = size
+	store.compactedSize = size
+
+	return nil
+}
+
+func (store *FileGameStore) Save(record GameRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	store.memory.mutex.Lock()
+	defer store.memory.mutex.Unlock()
+
+	if _, err = store.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	store.memory.records[record.Id] = copyRecord(record)
+	store.size += int64(len(line)) + 1
+
+	if store.size > compactionRatio*max(store.compactedSize, minCompactedSize) {
+		return store.compact()
+	}
+
+	return nil
+}
+
+func (store *FileGameStore) Load(id string) (GameRecord, bool, error) {
+	return store.memory.Load(id)
+}
+
+func (store *FileGameStore) InProgress() ([]GameRecord, error) {
+	return store.memory.InProgress()
+}
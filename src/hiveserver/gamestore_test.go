@@ -0,0 +1,156 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func placement(pieceType hivegame.HivePieceType, q, r int) HiveMove {
+	return HiveMove{
+		MoveType:  MoveTypePlacement,
+		Placement: &HivePlacement{PieceType: pieceType, Position: hivegame.HexVectorInt{Q: q, R: r}},
+	}
+}
+
+func testRecord(id string) GameRecord {
+	return GameRecord{
+		Id:          id,
+		BlackPlayer: 1,
+		WhitePlayer: 2,
+		TimeControl: &TimeControl{Kind: ClockFischer, Initial: time.Minute, Increment: time.Second},
+		Variant:     DefaultVariant,
+		Moves:       []HiveMove{placement(hivegame.PieceTypeQueenBee, 0, 0), placement(hivegame.PieceTypeQueenBee, 1, 0)},
+		Chat:        []ChatMessage{{Color: hivegame.ColorBlack, Text: "good luck", Sent: time.Now().UTC()}},
+		Clock:       &ClockState{Kind: ClockFischer, BlackMs: 59000, WhiteMs: 58000},
+		Created:     time.Now().UTC().Truncate(time.Second),
+		Updated:     time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestMemoryGameStoreCopiesRecords(t *testing.T) {
+	store := NewMemoryGameStore()
+	record := testRecord("ABC")
+
+	if err := store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+
+	record.Moves[0] = placement(hivegame.PieceTypeSpider, 5, 5)
+	record.Chat[0].Text = "changed"
+
+	loaded, ok, err := store.Load("ABC")
+	if err != nil || !ok {
+		t.Fatalf("Failed to load the saved record: %v", err)
+	}
+
+	if loaded.Moves[0].Placement.PieceType != hivegame.PieceTypeQueenBee {
+		t.Fatalf("The store shares its move list with the caller")
+	}
+
+	if loaded.Chat[0].Text != "good luck" {
+		t.Fatalf("The store shares its chat with the caller")
+	}
+
+	if _, ok, _ = store.Load("XYZ"); ok {
+		t.Fatalf("Loaded a record that was never saved")
+	}
+}
+
+func TestFileGameStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.jsonl")
+
+	store, err := OpenFileGameStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := testRecord("DONE")
+	finished.Result = &GameResult{Reason: ReasonResigned}
+
+	inProgress := testRecord("LIVE")
+
+	for _, record := range []GameRecord{finished, testRecord("LIVE"), inProgress} {
+		if err = store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inProgress.Moves = append(inProgress.Moves, placement(hivegame.PieceTypeSoldierAnt, -1, 0))
+	if err = store.Save(inProgress); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileGameStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok, err := reopened.Load("LIVE")
+	if err != nil || !ok {
+		t.Fatalf("Failed to load the saved record: %v", err)
+	}
+
+	if len(loaded.Moves) != 3 || len(loaded.Chat) != 1 || *loaded.Clock != *inProgress.Clock ||
+		*loaded.TimeControl != *inProgress.TimeControl || !loaded.Created.Equal(inProgress.Created) {
+		t.Fatalf("Record did not survive the round trip: %+v", loaded)
+	}
+
+	records, err := reopened.InProgress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Id != "LIVE" {
+		t.Fatalf("Expected only LIVE to be in progress, got %+v", records)
+	}
+
+	lines, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := bytes.Count(lines, []byte{'\n'}); count != 2 {
+		t.Fatalf("Expected the file to be compacted to 2 lines when opened, got %d", count)
+	}
+}
+
+func TestFileGameStoreCompactsAsItGrows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.jsonl")
+
+	store, err := OpenFileGameStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := testRecord("LONG")
+
+	for i := 0; i < 500; i++ {
+		record.Moves = append(record.Moves, placement(hivegame.PieceTypeSoldierAnt, i, -i))
+
+		if err = store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() > compactionRatio*minCompactedSize+store.compactedSize {
+		t.Fatalf("File grew to %d bytes for a record of %d bytes", info.Size(), store.compactedSize)
+	}
+
+	reopened, err := OpenFileGameStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded, _, _ := reopened.Load("LONG"); len(loaded.Moves) != len(record.Moves) {
+		t.Fatalf("Expected %d moves after compacting, got %d", len(record.Moves), len(loaded.Moves))
+	}
+}
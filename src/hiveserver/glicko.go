@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Glicko-2 as described by Mark Glickman in "Example of the Glicko-2 system", with each game rated
+// as it finishes rather than in batches
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+	// RatingPeriod is how long a player can go without playing before their deviation grows by one
+	// period's worth of volatility
+	RatingPeriod = 7 * 24 * time.Hour
+	// glickoTau limits how quickly volatility changes
+	glickoTau       = 0.5
+	glickoScale     = 173.7178
+	glickoTolerance = 0.000001
+)
+
+type Rating struct {
+	Rating     float64   `json:"rating"`
+	Deviation  float64   `json:"deviation"`
+	Volatility float64   `json:"volatility"`
+	Games      int       `json:"games"`
+	Updated    time.Time `json:"updated"`
+}
+
+// glickoOutcome is one game against an opponent, with a score of 1 for a win, 0.5 for a draw and 0
+// for a loss
+type glickoOutcome struct {
+	opponent Rating
+	score    float64
+}
+
+func NewRating() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// Provisional is true while the rating is too uncertain to be ranked
+func (rating Rating) Provisional() bool {
+	return rating.Deviation > ProvisionalDeviation
+}
+
+// At is the rating as it stands at the given time, with its deviation grown for each whole rating
+// period since it was last updated
+func (rating Rating) At(now time.Time) Rating {
+	if rating.Updated.IsZero() {
+		return rating
+	}
+
+	periods := float64(now.Sub(rating.Updated) / RatingPeriod)
+	phi := rating.Deviation / glickoScale
+	phi = math.Sqrt(phi*phi + periods*rating.Volatility*rating.Volatility)
+
+	rating.Deviation = math.Min(phi*glickoScale, DefaultDeviation)
+
+	return rating
+}
+
+// glicko2 rates a player on the outcomes of a rating period, of which there must be at least one
+func glicko2(rating Rating, outcomes []glickoOutcome) Rating {
+	mu := (rating.Rating - DefaultRating) / glickoScale
+	phi := rating.Deviation / glickoScale
+	sigma := rating.Volatility
+
+	var inverseVariance, improvement float64
+
+	for _, outcome := range outcomes {
+		muJ := (outcome.opponent.Rating - DefaultRating) / glickoScale
+		phiJ := outcome.opponent.Deviation / glickoScale
+
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		expected := 1 / (1 + math.Exp(-g*(mu-muJ)))
+
+		inverseVariance += g * g * expected * (1 - expected)
+		improvement += g * (outcome.score - expected)
+	}
+
+	v := 1 / inverseVariance
+	delta := v * improvement
+
+	// find the new volatility by the Illinois algorithm
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+
+	A := a
+	var B float64
+
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+
+	fA, fB := f(A), f(B)
+
+	for math.Abs(B-A) > glickoTolerance {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+
+		B, fB = C, fC
+	}
+
+	newSigma := math.Exp(A / 2)
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	return Rating{
+		Rating:     newMu*glickoScale + DefaultRating,
+		Deviation:  math.Min(newPhi*glickoScale, DefaultDeviation),
+		Volatility: newSigma,
+		Games:      rating.Games + len(outcomes),
+		Updated:    rating.Updated,
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestGlickmanExample checks the worked example in Glickman's "Example of the Glicko-2 system"
+func TestGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+
+	rated := glicko2(player, []glickoOutcome{
+		{opponent: Rating{Rating: 1400, Deviation: 30}, score: 1},
+		{opponent: Rating{Rating: 1550, Deviation: 100}, score: 0},
+		{opponent: Rating{Rating: 1700, Deviation: 300}, score: 0},
+	})
+
+	if math.Abs(rated.Rating-1464.06) > 0.01 {
+		t.Errorf("Expected a rating of 1464.06, got %.4f", rated.Rating)
+	}
+
+	if math.Abs(rated.Deviation-151.52) > 0.01 {
+		t.Errorf("Expected a deviation of 151.52, got %.4f", rated.Deviation)
+	}
+
+	if math.Abs(rated.Volatility-0.05999) > 0.00001 {
+		t.Errorf("Expected a volatility of 0.05999, got %.6f", rated.Volatility)
+	}
+
+	if rated.Games != 3 {
+		t.Errorf("Expected 3 games, got %d", rated.Games)
+	}
+}
+
+func TestGlickoDrawBetweenEquals(t *testing.T) {
+	rated := glicko2(NewRating(), []glickoOutcome{{opponent: NewRating(), score: 0.5}})
+
+	if math.Abs(rated.Rating-DefaultRating) > 1e-9 {
+		t.Errorf("A draw between equal players moved the rating to %.4f", rated.Rating)
+	}
+
+	if rated.Deviation >= DefaultDeviation {
+		t.Errorf("Playing a game did not narrow the deviation")
+	}
+}
+
+func TestRatingDeviationGrowsWithTime(t *testing.T) {
+	updated := time.Now()
+	rating := Rating{Rating: 1600, Deviation: 50, Volatility: 0.06, Updated: updated}
+
+	if rating.At(updated.Add(RatingPeriod/2)).Deviation != 50 {
+		t.Errorf("Deviation grew before a whole rating period had passed")
+	}
+
+	later := rating.At(updated.Add(10 * RatingPeriod))
+	expected := math.Sqrt(50*50 + 10*0.06*0.06*glickoScale*glickoScale)
+
+	if math.Abs(later.Deviation-expected) > 1e-9 || later.Rating != 1600 {
+		t.Errorf("Expected a deviation of %.4f after ten periods, got %.4f", expected, later.Deviation)
+	}
+
+	if rating.At(updated.Add(2000*RatingPeriod)).Deviation != DefaultDeviation {
+		t.Errorf("Deviation grew past that of a new player")
+	}
+}
+
+func TestRatingCategory(t *testing.T) {
+	tests := []struct {
+		control  *TimeControl
+		category string
+	}{
+		{nil, CategoryUntimed},
+		{&TimeControl{Kind: ClockFischer, Initial: time.Minute, Increment: time.Second}, CategoryBullet},
+		{&TimeControl{Kind: ClockFischer, Initial: 3 * time.Minute, Increment: 2 * time.Second}, CategoryBlitz},
+		{&TimeControl{Kind: ClockSuddenDeath, Initial: 15 * time.Minute}, CategoryRapid},
+		{&TimeControl{Kind: ClockFischer, Initial: 30 * time.Minute}, CategoryClassical},
+	}
+
+	for _, test := range tests {
+		if category := RatingCategory(test.control); category != test.category {
+			t.Errorf("Expected %+v to be %s, got %s", test.control, test.category, category)
+		}
+	}
+}
@@ -3,16 +3,23 @@ package main
 import (
 	"HiveServer/src/hivegame"
 	"github.com/gorilla/websocket"
+	"log"
 	"sync"
 	"time"
 )
 
 type HostedGameState struct {
 	// Mapping of the game id string to a *HostedGame
-	games sync.Map
+	games    sync.Map
+	store    GameStore
+	sessions *SessionStore
+	accounts *AccountStore
+	lobby    *LobbyHub
+	ratings  *RatingStore
 }
 
 type HostedGame struct {
+	id                    string
 	whitePlayer           uint64
 	whiteConn             *websocket.Conn
 	blackPlayer           uint64
@@ -21,17 +28,120 @@ type HostedGame struct {
 	blackLastDisconnected *time.Time
 	disconnectMutex       sync.Mutex
 	onDisconnect          chan hivegame.HiveColor
-	shutdown              chan struct{}
 	hiveGame              hivegame.HiveGame
-	condition             *sync.Cond
+	// every move recorded so far, including passes
+	moves []HiveMove
+	// the colour of the player with a draw offer open to their opponent, if any
+	drawOfferedBy *hivegame.HiveColor
+	// the colour of the player with a rematch offer open to their opponent, once the game is over
+	rematchOfferedBy *hivegame.HiveColor
+	// closes both connections once the rematch window passes
+	rematchTimer *time.Timer
+	chat         []ChatMessage
+	// indexed by colour
+	chatLimiters [2]chatLimiter
+	// set while there is chat waiting to be saved
+	chatSaveTimer *time.Timer
+	completed     bool
+	// closed once the game is completed
+	done chan struct{}
+	// nil for games without a time control
+	clock     *GameClock
+	flagTimer *time.Timer
+	// called when a player runs out of time, as there is no handler around to do it
+	onFlag func()
+	// spectators see the game as it was spectatorDelay ago, so that they cannot pass moves on to
+	// the players as they happen
+	spectators     map[*websocket.Conn]bool
+	spectatorDelay time.Duration
+	spectatorQueue []spectatorMessage
+	spectatorGame  hivegame.HiveGame
+	spectatorMoves []HiveMove
+	spectatorClock *ClockState
+	options        HostedGameOptions
+	store          GameStore
+	created        time.Time
+	// nil until the game is over
+	result *GameResult
+	// when the server restarted, for a game resumed from the store
+	resumedAt *time.Time
+	// whether each player of a resumed game has come back since the restart
+	blackReturned bool
+	whiteReturned bool
+	condition     *sync.Cond
 }
 
-func NewHostedGame() *HostedGame {
-	return &HostedGame{
-		hiveGame:     hivegame.CreateHiveGame(),
-		condition:    sync.NewCond(&sync.Mutex{}),
-		onDisconnect: make(chan hivegame.HiveColor, 1),
-		shutdown:     make(chan struct{}, 1),
+type HostedGameOptions struct {
+	// nil for a game without a clock
+	TimeControl    *TimeControl
+	SpectatorDelay time.Duration
+	// Public games are listed in the lobby; private ones can only be found by their id
+	Public      bool
+	Variant     string
+	CreatorId   uint64
+	CreatorName string
+	// BlackSeat and WhiteSeat hold a colour for a player. If either is set, anyone else can only
+	// take a seat held for nobody.
+	BlackSeat uint64
+	WhiteSeat uint64
+}
+
+type spectatorMessage struct {
+	at      time.Time
+	message PlayMessage
+}
+
+func NewHostedGame(id string, options HostedGameOptions, store GameStore) *HostedGame {
+	game := &HostedGame{
+		id:             id,
+		options:        options,
+		store:          store,
+		created:        time.Now(),
+		hiveGame:       CreateVariantGame(options.Variant),
+		condition:      sync.NewCond(&sync.Mutex{}),
+		onDisconnect:   make(chan hivegame.HiveColor, 1),
+		done:           make(chan struct{}),
+		spectators:     make(map[*websocket.Conn]bool),
+		spectatorDelay: options.SpectatorDelay,
+		spectatorGame:  CreateVariantGame(options.Variant),
+	}
+
+	if options.TimeControl != nil {
+		game.clock = NewGameClock(*options.TimeControl)
+		game.spectatorClock = game.clockState()
+	}
+
+	return game
+}
+
+// Record is the game as it is kept in the store. The caller must hold the game's lock.
+func (hg *HostedGame) Record() GameRecord {
+	return GameRecord{
+		Id:             hg.id,
+		BlackPlayer:    hg.blackPlayer,
+		WhitePlayer:    hg.whitePlayer,
+		TimeControl:    hg.options.TimeControl,
+		SpectatorDelay: hg.options.SpectatorDelay,
+		Public:         hg.options.Public,
+		Variant:        hg.options.Variant,
+		CreatorId:      hg.options.CreatorId,
+		CreatorName:    hg.options.CreatorName,
+		BlackSeat:      hg.options.BlackSeat,
+		WhiteSeat:      hg.options.WhiteSeat,
+		Moves:          hg.moves,
+		Chat:           hg.chat,
+		Clock:          hg.clockState(),
+		Result:         hg.result,
+		Created:        hg.created,
+		Updated:        time.Now(),
+	}
+}
+
+// Persist saves the game to the store. A failure is only logged, as the game can carry on without
+// it. The caller must hold the game's lock.
+func (hg *HostedGame) Persist() {
+	if err := hg.store.Save(hg.Record()); err != nil {
+		log.Printf("Error saving game %s: %v\n", hg.id, err)
 	}
 }
 
@@ -44,7 +154,401 @@ func (hg *HostedGame) RecordMove(move *HiveMove) bool {
 		success = hg.hiveGame.MoveTile(move.Movement.From, move.Movement.To)
 	case MoveTypePlacement:
 		success = hg.hiveGame.PlaceTile(move.Placement.Position, move.Placement.PieceType)
+	case MoveTypePass:
+		success = hg.hiveGame.Pass()
+	}
+
+	if success {
+		hg.moves = append(hg.moves, *move)
 	}
 
 	return success
 }
+
+// RecordForcedPass passes for the colour to move if it has no legal moves, and returns the pass so
+// that it can be announced to the players
+func (hg *HostedGame) RecordForcedPass() *HiveMove {
+	if !hg.hiveGame.Pass() {
+		return nil
+	}
+
+	pass := HiveMove{MoveType: MoveTypePass}
+	hg.moves = append(hg.moves, pass)
+
+	return &pass
+}
+
+// StateFor is everything a player of the given colour needs to pick the game up from where it is.
+// The caller must hold the game's lock.
+func (hg *HostedGame) StateFor(color hivegame.HiveColor) *GameState {
+	moves := make([]HiveMove, len(hg.moves))
+	copy(moves, hg.moves)
+
+	chat := make([]ChatMessage, len(hg.chat))
+	copy(chat, hg.chat)
+
+	return &GameState{
+		Game:        hg.hiveGame.Clone(),
+		Moves:       moves,
+		Color:       color,
+		ColorToMove: hg.hiveGame.ColorToMove,
+		Clock:       hg.clockState(),
+		Chat:        chat,
+	}
+}
+
+// StartClock starts the clock of the colour to move, once both players have joined. onGameComplete
+// is called if a player runs out of time. The caller must hold the game's lock.
+func (hg *HostedGame) StartClock(onGameComplete func()) {
+	if hg.clock == nil {
+		return
+	}
+
+	hg.onFlag = onGameComplete
+	hg.clock.Start(hg.hiveGame.ColorToMove, time.Now())
+	hg.scheduleFlag()
+}
+
+// scheduleFlag sets a timer to end the game when the colour to move runs out of time, replacing
+// the one set for the last turn
+func (hg *HostedGame) scheduleFlag() {
+	if hg.flagTimer != nil {
+		hg.flagTimer.Stop()
+	}
+
+	untilFlag, color := hg.clock.UntilFlag(time.Now())
+
+	hg.flagTimer = time.AfterFunc(untilFlag, func() {
+		hg.condition.L.Lock()
+		defer hg.condition.L.Unlock()
+
+		// the timer may have fired just as the player moved
+		if hg.completed || !hg.clock.Flagged(color, time.Now()) {
+			return
+		}
+
+		winner := opponentOf(color)
+		hg.Complete(&winner, ReasonTimeout)
+		hg.onFlag()
+	})
+}
+
+func (hg *HostedGame) clockState() *ClockState {
+	if hg.clock == nil {
+		return nil
+	}
+
+	return hg.clock.State(time.Now())
+}
+
+// PlayMove records a move sent by the player of the given colour, so long as it is their turn and
+// the move is legal, and only then relays it to their opponent. Both players are sent the state of
+// the game that results. Returns true if the move ended the game.
+//
+// The caller must hold the game's lock.
+func (hg *HostedGame) PlayMove(color hivegame.HiveColor, move *HiveMove) (over bool, err error) {
+	if hg.completed {
+		return false, nil
+	}
+
+	if hg.clock != nil && hg.clock.Flagged(color, time.Now()) {
+		// the move came in after the player's time ran out, but before the timer noticed
+		winner := opponentOf(color)
+		hg.Complete(&winner, ReasonTimeout)
+		return true, nil
+	}
+
+	if color != hg.hiveGame.ColorToMove || !move.valid() || !hg.RecordMove(move) {
+		return false, hg.send(color, PlayMessage{
+			Event: EventRejectedMove,
+		})
+	}
+
+	if hg.clock != nil {
+		hg.clock.Switch(time.Now())
+	}
+
+	err = hg.send(color, PlayMessage{
+		Event: EventAcceptedMove,
+		Move:  move,
+		Game:  &hg.hiveGame,
+		Clock: hg.clockState(),
+	})
+
+	hg.sendOrLog(opponentOf(color), PlayMessage{
+		Event: EventPlayMove,
+		Move:  move,
+		Game:  &hg.hiveGame,
+		Clock: hg.clockState(),
+	})
+
+	hg.broadcastMove(move)
+	hg.Persist()
+
+	// making a move turns down any draw the opponent offered
+	hg.drawOfferedBy = nil
+
+	if over, winner := hg.hiveGame.IsOver(); over {
+		hg.Complete(&winner, ReasonSurrounded)
+		return true, err
+	}
+
+	if pass := hg.RecordForcedPass(); pass != nil {
+		if hg.clock != nil {
+			hg.clock.Switch(time.Now())
+		}
+
+		// the opponent has no legal moves, so both players need to know the turn came back
+		for _, c := range []hivegame.HiveColor{hivegame.ColorBlack, hivegame.ColorWhite} {
+			hg.sendOrLog(c, PlayMessage{
+				Event: EventPlayMove,
+				Move:  pass,
+				Game:  &hg.hiveGame,
+				Clock: hg.clockState(),
+			})
+		}
+
+		hg.broadcastMove(pass)
+		hg.Persist()
+	}
+
+	if hg.clock != nil {
+		hg.scheduleFlag()
+	}
+
+	return false, err
+}
+
+// Resign ends the game as a loss for the player of the given colour. Returns true if this ended
+// the game. The caller must hold the game's lock.
+func (hg *HostedGame) Resign(color hivegame.HiveColor) bool {
+	if hg.completed {
+		return false
+	}
+
+	winner := opponentOf(color)
+	hg.Complete(&winner, ReasonResigned)
+
+	return true
+}
+
+// OfferDraw passes a draw offer on to the opponent, which stays open until they accept or decline
+// it, or make a move. The caller must hold the game's lock.
+func (hg *HostedGame) OfferDraw(color hivegame.HiveColor) {
+	if hg.completed || hg.drawOfferedBy != nil {
+		return
+	}
+
+	hg.drawOfferedBy = &color
+
+	hg.sendOrLog(opponentOf(color), PlayMessage{
+		Event: EventOfferDraw,
+	})
+}
+
+// AcceptDraw ends the game as a draw if the opponent of the given colour has offered one. Returns
+// true if this ended the game. The caller must hold the game's lock.
+func (hg *HostedGame) AcceptDraw(color hivegame.HiveColor) bool {
+	if hg.completed || hg.drawOfferedBy == nil || *hg.drawOfferedBy != opponentOf(color) {
+		return false
+	}
+
+	hg.Complete(nil, ReasonAgreedDraw)
+
+	return true
+}
+
+// DeclineDraw turns down a draw offered by the opponent of the given colour. The caller must hold
+// the game's lock.
+func (hg *HostedGame) DeclineDraw(color hivegame.HiveColor) {
+	if hg.completed || hg.drawOfferedBy == nil || *hg.drawOfferedBy != opponentOf(color) {
+		return
+	}
+
+	hg.drawOfferedBy = nil
+
+	hg.sendOrLog(opponentOf(color), PlayMessage{
+		Event: EventDeclineDraw,
+	})
+}
+
+// Complete tells both players the result of the game, leaving their connections open for
+// RematchWindow in case they want to play again. A nil winner means the game was drawn, or
+// abandoned. The caller must hold the game's lock.
+func (hg *HostedGame) Complete(winner *hivegame.HiveColor, reason string) {
+	hg.completed = true
+	hg.result = &GameResult{
+		Winner: winner,
+		Reason: reason,
+	}
+	close(hg.done)
+
+	hg.Persist()
+
+	if hg.flagTimer != nil {
+		hg.flagTimer.Stop()
+	}
+
+	for _, c := range []hivegame.HiveColor{hivegame.ColorBlack, hivegame.ColorWhite} {
+		hg.sendOrLog(c, PlayMessage{
+			Event: EventGameCompleted,
+			Complete: &GameComplete{
+				Won:    winner != nil && *winner == c,
+				Draw:   winner == nil && reason != ReasonAbandoned,
+				Reason: reason,
+			},
+		})
+	}
+
+	hg.rematchTimer = time.AfterFunc(RematchWindow, func() {
+		hg.condition.L.Lock()
+		defer hg.condition.L.Unlock()
+
+		hg.closeConnections()
+	})
+
+	hg.broadcast(PlayMessage{
+		Event: EventGameCompleted,
+		Complete: &GameComplete{
+			Draw:   winner == nil && reason != ReasonAbandoned,
+			Reason: reason,
+			Winner: winner,
+		},
+	})
+}
+
+// AddSpectator sends the game as spectators currently see it to a new spectator, who is then sent
+// each move and the result along with the other spectators. Returns false if the game is already
+// over. The caller must hold the game's lock.
+func (hg *HostedGame) AddSpectator(conn *websocket.Conn) (bool, error) {
+	if hg.completed {
+		return false, nil
+	}
+
+	hg.spectators[conn] = true
+
+	moves := make([]HiveMove, len(hg.spectatorMoves))
+	copy(moves, hg.spectatorMoves)
+
+	// chat is relayed to spectators as it is sent, so is not held back with the moves
+	chat := make([]ChatMessage, len(hg.chat))
+	copy(chat, hg.chat)
+
+	err := conn.WriteJSON(PlayMessage{
+		Event: EventState,
+		State: &GameState{
+			Game:        hg.spectatorGame.Clone(),
+			Moves:       moves,
+			ColorToMove: hg.spectatorGame.ColorToMove,
+			Clock:       hg.spectatorClock,
+			Chat:        chat,
+			Spectating:  true,
+		},
+	})
+
+	if err != nil {
+		delete(hg.spectators, conn)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RemoveSpectator stops sending the game to a spectator. The caller must hold the game's lock.
+func (hg *HostedGame) RemoveSpectator(conn *websocket.Conn) {
+	delete(hg.spectators, conn)
+}
+
+// broadcastMove sends a move to the spectators along with the game it left behind
+func (hg *HostedGame) broadcastMove(move *HiveMove) {
+	game := hg.hiveGame.Clone()
+
+	hg.broadcast(PlayMessage{
+		Event: EventPlayMove,
+		Move:  move,
+		Game:  &game,
+		Clock: hg.clockState(),
+	})
+}
+
+// broadcast queues a message for the spectators, to be sent once the spectator delay has passed.
+// Anything in the message must not change after it is queued.
+func (hg *HostedGame) broadcast(message PlayMessage) {
+	hg.spectatorQueue = append(hg.spectatorQueue, spectatorMessage{
+		at:      time.Now().Add(hg.spectatorDelay),
+		message: message,
+	})
+
+	if hg.spectatorDelay == 0 {
+		hg.flushSpectators()
+		return
+	}
+
+	time.AfterFunc(hg.spectatorDelay, func() {
+		hg.condition.L.Lock()
+		hg.flushSpectators()
+		hg.condition.L.Unlock()
+	})
+}
+
+// flushSpectators sends the spectators every queued message that is due, in the order they were
+// queued
+func (hg *HostedGame) flushSpectators() {
+	now := time.Now()
+
+	for len(hg.spectatorQueue) > 0 && !hg.spectatorQueue[0].at.After(now) {
+		message := hg.spectatorQueue[0].message
+		hg.spectatorQueue = hg.spectatorQueue[1:]
+
+		if message.Event == EventPlayMove {
+			hg.spectatorGame = *message.Game
+			hg.spectatorMoves = append(hg.spectatorMoves, *message.Move)
+			hg.spectatorClock = message.Clock
+		}
+
+		for conn := range hg.spectators {
+			if err := conn.WriteJSON(message); err != nil {
+				log.Printf("Error writing %s to spectator websocket: %v\n", message.Event, err)
+			}
+
+			if message.Event == EventGameCompleted {
+				_ = conn.Close()
+			}
+		}
+	}
+}
+
+func (hg *HostedGame) connOf(color hivegame.HiveColor) *websocket.Conn {
+	if color == hivegame.ColorBlack {
+		return hg.blackConn
+	}
+
+	return hg.whiteConn
+}
+
+// send writes a message to the player of the given colour if they are connected. Writes are only
+// made while holding the game's lock, as a connection only supports one writer at a time.
+func (hg *HostedGame) send(color hivegame.HiveColor, message PlayMessage) error {
+	conn := hg.connOf(color)
+
+	if conn == nil {
+		return nil
+	}
+
+	return conn.WriteJSON(message)
+}
+
+// sendOrLog is send for when a failed write is left for the reader of that connection to notice
+func (hg *HostedGame) sendOrLog(color hivegame.HiveColor, message PlayMessage) {
+	if err := hg.send(color, message); err != nil {
+		log.Printf("Error writing %s to websocket: %v\n", message.Event, err)
+	}
+}
+
+func opponentOf(color hivegame.HiveColor) hivegame.HiveColor {
+	if color == hivegame.ColorBlack {
+		return hivegame.ColorWhite
+	}
+
+	return hivegame.ColorBlack
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type HostedGameListHandler struct {
+	state *HostedGameState
+}
+
+func CreateHostedGameListHandler(hostedGameState *HostedGameState) *HostedGameListHandler {
+	return &HostedGameListHandler{
+		state: hostedGameState,
+	}
+}
+
+// HostedGameListing is an open public game as shown in the lobby. Connecting to PlayPath takes the
+// seat left in it.
+type HostedGameListing struct {
+	Id          string `json:"id"`
+	CreatorName string `json:"creatorName"`
+	Variant     string `json:"variant"`
+	// CreatorColor is the colour the creator plays as, or random if they left it to chance
+	CreatorColor string `json:"creatorColor"`
+	// nil for a game without a clock
+	TimeControl *TimeControlListing `json:"timeControl,omitempty"`
+	Created     time.Time           `json:"created"`
+	PlayPath    string              `json:"playPath"`
+}
+
+type TimeControlListing struct {
+	Kind        string `json:"kind"`
+	InitialMs   int64  `json:"initialMs"`
+	IncrementMs int64  `json:"incrementMs"`
+}
+
+func (h *HostedGameListHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	log.Println("GET /hosted-game/list")
+
+	err := json.NewEncoder(w).Encode(h.state.OpenListings())
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Listing describes the game for the lobby. Returns false if it should not be listed, as it is
+// private, already has both its players or its seats are held for particular players. The caller
+// must hold the game's lock.
+func (hg *HostedGame) Listing() (HostedGameListing, bool) {
+	seatsHeld := hg.options.BlackSeat != 0 && hg.options.WhiteSeat != 0
+
+	if !hg.options.Public || hg.completed || seatsHeld || (hg.blackPlayer != 0 && hg.whitePlayer != 0) {
+		return HostedGameListing{}, false
+	}
+
+	listing := HostedGameListing{
+		Id:          hg.id,
+		CreatorName: hg.options.CreatorName,
+		Variant:     hg.options.Variant,
+		Created:     hg.created,
+		PlayPath:    "/hosted-game/play?id=" + hg.id,
+	}
+
+	switch hg.options.CreatorId {
+	case hg.options.BlackSeat:
+		listing.CreatorColor = ColorChoiceBlack
+	case hg.options.WhiteSeat:
+		listing.CreatorColor = ColorChoiceWhite
+	default:
+		listing.CreatorColor = ColorChoiceRandom
+	}
+
+	if timeControl := hg.options.TimeControl; timeControl != nil {
+		listing.TimeControl = &TimeControlListing{
+			Kind:        timeControl.Kind,
+			InitialMs:   timeControl.Initial.Milliseconds(),
+			IncrementMs: timeControl.Increment.Milliseconds(),
+		}
+	}
+
+	return listing, true
+}
+
+// DisplayName is how a player is shown to others: their account's display name, or a numbered
+// guest if they have no account
+func (state *HostedGameState) DisplayName(playerId uint64) string {
+	if state.accounts != nil {
+		if account, ok := state.accounts.ById(playerId); ok {
+			return account.DisplayName
+		}
+	}
+
+	return fmt.Sprintf("Guest %d", playerId)
+}
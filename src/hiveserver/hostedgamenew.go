@@ -2,12 +2,13 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
-	"github.com/golang-jwt/jwt/v5"
+	"errors"
 	"log"
 	"math/rand"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 type HostedGameNewHandler struct {
@@ -20,44 +21,53 @@ func CreateHostedGameNewHandler(hostedGameState *HostedGameState) *HostedGameNew
 	}
 }
 
+// Colours the creator of a game can ask to play as
+const (
+	ColorChoiceBlack  = "black"
+	ColorChoiceWhite  = "white"
+	ColorChoiceRandom = "random"
+)
+
 type HostedGameNewResponse struct {
 	Id string `json:"id"`
 }
 
 func (h *HostedGameNewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /hosted-game/new")
+	log.Printf("GET /hosted-game/new?%s\n", r.URL.RawQuery)
 
 	var err error
-	var givenToken string
+	var claims *PlayerClaims
 
-	_, err = fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &givenToken)
+	claims, err = AuthenticateRequest(r, h.state.sessions)
 
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	_, err = jwt.Parse(givenToken, func(_ *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	var options HostedGameOptions
+
+	options, err = parseHostedGameOptions(r.URL.Query(), claims.PlayerId)
 
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	id := generateGameId()
+	options.CreatorName = h.state.DisplayName(claims.PlayerId)
 
-	if _, ok := h.state.games.Load(id); ok {
-		log.Println("Generated an id that is already in use; time to re-think this solution")
+	var game *HostedGame
+
+	game, err = h.state.Host(options)
+
+	if err != nil {
+		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	h.state.games.Store(id, NewHostedGame())
-
 	err = json.NewEncoder(w).Encode(HostedGameNewResponse{
-		Id: id,
+		Id: game.id,
 	})
 
 	if err != nil {
@@ -66,6 +76,99 @@ func (h *HostedGameNewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// parseHostedGameOptions reads the time control, the delay in seconds before spectators see each
+// move, whether the game is public, its variant and the colour its creator plays as from the query
+// parameters
+func parseHostedGameOptions(query url.Values, creatorId uint64) (HostedGameOptions, error) {
+	var options HostedGameOptions
+	var err error
+
+	options.CreatorId = creatorId
+	options.Public = query.Get("public") == "true"
+
+	// with no choice, whoever joins first is given a random colour
+	switch query.Get("color") {
+	case ColorChoiceBlack:
+		options.BlackSeat = creatorId
+	case ColorChoiceWhite:
+		options.WhiteSeat = creatorId
+	case ColorChoiceRandom, "":
+	default:
+		return options, errors.New("colour must be black, white or random")
+	}
+
+	options.Variant = DefaultVariant
+	if query.Has("variant") {
+		options.Variant = query.Get("variant")
+
+		if !ValidVariant(options.Variant) {
+			return options, errors.New("unknown variant")
+		}
+	}
+
+	options.TimeControl, err = ParseTimeControl(query)
+	if err != nil {
+		return options, err
+	}
+
+	if query.Has("spectatorDelay") {
+		delay, err := strconv.Atoi(query.Get("spectatorDelay"))
+		if err != nil || delay < 0 {
+			return options, errors.New("spectator delay must be a number of seconds")
+		}
+
+		options.SpectatorDelay = time.Duration(delay) * time.Second
+	}
+
+	return options, nil
+}
+
+// Host creates a game and makes it available to play, announcing it in the lobby if it is public
+func (state *HostedGameState) Host(options HostedGameOptions) (*HostedGame, error) {
+	id := generateGameId()
+	game := NewHostedGame(id, options, state.store)
+
+	if _, loaded := state.games.LoadOrStore(id, game); loaded {
+		return nil, errors.New("generated an id that is already in use; time to re-think this solution")
+	}
+
+	game.condition.L.Lock()
+	game.Persist()
+	listing, listed := game.Listing()
+	game.condition.L.Unlock()
+
+	if listed {
+		state.lobby.Publish(LobbyEvent{
+			Event: LobbyEventGameCreated,
+			Game:  &listing,
+		})
+	}
+
+	return game, nil
+}
+
+// AbandonUnlessJoined abandons a game that its players have not both joined within the timeout, so
+// that games hosted for players who never come do not stay hosted for good
+func (state *HostedGameState) AbandonUnlessJoined(game *HostedGame, timeout time.Duration) {
+	time.AfterFunc(timeout, func() {
+		game.condition.L.Lock()
+		defer game.condition.L.Unlock()
+
+		if game.completed || (game.blackPlayer != 0 && game.whitePlayer != 0) {
+			return
+		}
+
+		log.Printf("Game %s was not joined in time, so it has been abandoned\n", game.id)
+
+		game.Complete(nil, ReasonAbandoned)
+		game.closeConnections()
+		// wakes a player waiting for their opponent
+		game.condition.Broadcast()
+
+		state.OnGameCompleted(game.id)
+	})
+}
+
 func generateGameId() string {
 	const Length = 6
 
@@ -2,12 +2,10 @@ package main
 
 import (
 	"HiveServer/src/hivegame"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"log"
 	"math/rand"
 	"net/http"
-	"os"
 	"time"
 )
 
@@ -78,18 +76,25 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var token *jwt.Token
+	var claims *PlayerClaims
 
-	token, err = jwt.Parse(*givenToken, func(_ *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	claims, err = Authenticate(*givenToken, h.state.sessions)
 
 	if err != nil {
 		_ = conn.Close()
 		return
 	}
 
-	playerId := uint64(token.Claims.(jwt.MapClaims)["id"].(float64))
+	playerId := claims.PlayerId
+
+	// signing out ends the player's connections on that session along with it
+	unwatch := h.state.sessions.Watch(claims.SessionId, func() {
+		_ = conn.Close()
+	})
+	defer unwatch()
+
+	h.state.lobby.PlayerConnected()
+	defer h.state.lobby.PlayerDisconnected()
 
 	var playerColor hivegame.HiveColor
 	var isReconnect = false
@@ -100,11 +105,19 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		switch playerId {
 		case game.blackPlayer:
 			game.blackConn = conn
+			game.disconnectMutex.Lock()
 			game.blackLastDisconnected = nil
+			game.disconnectMutex.Unlock()
+			game.blackReturned = true
+			playerColor = hivegame.ColorBlack
 			isReconnect = true
 		case game.whitePlayer:
 			game.whiteConn = conn
+			game.disconnectMutex.Lock()
 			game.whiteLastDisconnected = nil
+			game.disconnectMutex.Unlock()
+			game.whiteReturned = true
+			playerColor = hivegame.ColorWhite
 			isReconnect = true
 		default:
 			// player is trying to play a game that's not theirs
@@ -113,9 +126,56 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			return
 		}
 
+		game.ResumeClock()
+
 		goto unlock
 	}
 
+	if game.options.BlackSeat != 0 || game.options.WhiteSeat != 0 {
+		// seats were handed out when the game was made, so the player takes theirs, or else the one
+		// held for nobody
+		firstToJoin := game.blackPlayer == 0 && game.whitePlayer == 0
+
+		switch {
+		case playerId == game.options.BlackSeat || playerId == game.blackPlayer:
+			playerColor = hivegame.ColorBlack
+		case playerId == game.options.WhiteSeat || playerId == game.whitePlayer:
+			playerColor = hivegame.ColorWhite
+		case game.options.BlackSeat == 0 && game.blackPlayer == 0:
+			playerColor = hivegame.ColorBlack
+		case game.options.WhiteSeat == 0 && game.whitePlayer == 0:
+			playerColor = hivegame.ColorWhite
+		default:
+			// player is trying to take a seat held for someone else
+			game.condition.L.Unlock()
+			_ = conn.Close()
+			return
+		}
+
+		if playerColor == hivegame.ColorBlack {
+			game.blackPlayer = playerId
+			game.blackConn = conn
+		} else {
+			game.whitePlayer = playerId
+			game.whiteConn = conn
+		}
+
+		game.Persist()
+
+		if game.blackPlayer != 0 && game.whitePlayer != 0 {
+			goto opponentJoined
+		}
+
+		if listing, listed := game.Listing(); listed && firstToJoin {
+			h.state.lobby.Publish(LobbyEvent{
+				Event: LobbyEventGameJoined,
+				Game:  &listing,
+			})
+		}
+
+		goto waitForOpponentJoin
+	}
+
 	if game.blackPlayer == 0 && game.whitePlayer == 0 {
 		// player is the first to join this game
 		if rand.Intn(2) == 0 {
@@ -127,9 +187,20 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			game.whiteConn = conn
 			playerColor = hivegame.ColorWhite
 		}
+
+		game.Persist()
+
+		if listing, listed := game.Listing(); listed {
+			h.state.lobby.Publish(LobbyEvent{
+				Event: LobbyEventGameJoined,
+				Game:  &listing,
+			})
+		}
 	} else if game.blackPlayer == 0 {
 		if game.whitePlayer == playerId {
 			// rejoining before the other player has joined
+			game.whiteConn = conn
+			playerColor = hivegame.ColorWhite
 			goto waitForOpponentJoin
 		}
 
@@ -137,11 +208,12 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		game.blackPlayer = playerId
 		game.blackConn = conn
 		playerColor = hivegame.ColorBlack
-		game.condition.Signal()
-		goto unlock
+		goto opponentJoined
 	} else if game.whitePlayer == 0 {
 		if game.blackPlayer == playerId {
 			// rejoining before the other player has joined
+			game.blackConn = conn
+			playerColor = hivegame.ColorBlack
 			goto waitForOpponentJoin
 		}
 
@@ -149,39 +221,45 @@ func (h *HostedGamePlayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		game.whitePlayer = playerId
 		game.whiteConn = conn
 		playerColor = hivegame.ColorWhite
-		game.condition.Signal()
-		goto unlock
+		goto opponentJoined
 	}
 
 waitForOpponentJoin:
-	for game.blackPlayer == 0 || game.whitePlayer == 0 {
+	for !game.completed && (game.blackPlayer == 0 || game.whitePlayer == 0) {
 		// we were the first to join and are waiting for our opponent to join
 		game.condition.Wait()
+	}
 
-		go game.WatchForDisconnect(func() {
-			h.state.OnGameCompleted(gameId)
-		})
+	if game.completed {
+		// the opponent never came, and the connection has been closed along with the game
+		game.condition.L.Unlock()
+		return
 	}
 
-unlock:
-	game.condition.L.Unlock()
+	goto unlock
 
-	var oppConn *websocket.Conn
-	if game.blackPlayer == playerId {
-		oppConn = game.whiteConn
-	} else {
-		oppConn = game.blackConn
+opponentJoined:
+	game.Persist()
+
+	if game.options.Public {
+		h.state.lobby.Publish(LobbyEvent{
+			Event:  LobbyEventGameStarted,
+			GameId: gameId,
+		})
 	}
+	go game.WatchForDisconnect(func() {
+		h.state.OnGameCompleted(gameId)
+	})
+	game.StartClock(func() {
+		h.state.OnGameCompleted(gameId)
+	})
+	game.condition.Broadcast()
 
+unlock:
 	if isReconnect {
-		if oppConn != nil {
-			err = oppConn.WriteJSON(PlayMessage{
-				Event: EventReconnect,
-			})
-			if err != nil {
-				goto wsWriteError
-			}
-		}
+		game.sendOrLog(opponentOf(playerColor), PlayMessage{
+			Event: EventReconnect,
+		})
 	} else {
 		err = conn.WriteJSON(PlayMessage{
 			Event: EventConnect,
@@ -189,27 +267,37 @@ unlock:
 				Color: playerColor,
 			},
 		})
-		if err != nil {
-			goto wsWriteError
-		}
+	}
+
+	if err == nil {
+		err = conn.WriteJSON(PlayMessage{
+			Event: EventState,
+			State: game.StateFor(playerColor),
+		})
+	}
+
+	game.condition.L.Unlock()
+
+	if err != nil {
+		goto wsWriteError
 	}
 
 	for {
+		message = PlayMessage{}
 		err = conn.ReadJSON(&message)
 		if err != nil {
 			// player has disconnected; or the server terminated the connection because
 			// the game is over
 
-			select {
-			case <-game.shutdown:
-				// only happens when the server ended the game because the opponent disconnected
-				return
-			default:
-				break
-			}
+			game.condition.L.Lock()
 
-			if over, _ := game.hiveGame.IsOver(); !over {
+			// a stale connection closing after its player has reconnected is not a disconnect
+			disconnected := !game.completed && game.connOf(playerColor) == conn
+
+			if disconnected {
 				when := time.Now()
+
+				game.disconnectMutex.Lock()
 				if playerColor == hivegame.ColorBlack {
 					game.blackConn = nil
 					game.blackLastDisconnected = &when
@@ -217,65 +305,70 @@ unlock:
 					game.whiteConn = nil
 					game.whiteLastDisconnected = &when
 				}
+				game.disconnectMutex.Unlock()
 
-				game.onDisconnect <- playerColor
-
-				err = oppConn.WriteJSON(PlayMessage{
+				game.sendOrLog(opponentOf(playerColor), PlayMessage{
 					Event: EventDisconnect,
 				})
-				if err != nil {
-					goto wsWriteError
+			} else if game.completed && game.connOf(playerColor) == conn {
+				// player left during the rematch window, so their opponent should stop waiting on them
+				if playerColor == hivegame.ColorBlack {
+					game.blackConn = nil
+				} else {
+					game.whiteConn = nil
 				}
+
+				game.sendOrLog(opponentOf(playerColor), PlayMessage{
+					Event: EventDisconnect,
+				})
 			}
 
-			break
-		}
+			game.condition.L.Unlock()
 
-		if message.Event == EventPlayMove {
-			err = oppConn.WriteJSON(PlayMessage{
-				Event: EventPlayMove,
-				Move:  message.Move,
-			})
-			if err != nil {
-				goto wsWriteError
+			if disconnected {
+				game.onDisconnect <- playerColor
 			}
 
-			if !game.RecordMove(message.Move) {
-				// the move was illegal
-				err = conn.WriteJSON(PlayMessage{
-					Event: EventRejectedMove,
-				})
-				if err != nil {
-					goto wsWriteError
-				}
-			} else if over, winner := game.hiveGame.IsOver(); over {
-				err = conn.WriteJSON(PlayMessage{
-					Event: EventGameCompleted,
-					Complete: &GameComplete{
-						Won: winner == playerColor,
-					},
-				})
-				if err != nil {
-					goto wsWriteError
-				}
+			break
+		}
 
-				err = oppConn.WriteJSON(PlayMessage{
-					Event: EventGameCompleted,
-					Complete: &GameComplete{
-						Won: winner != playerColor,
-					},
-				})
-				if err != nil {
-					goto wsWriteError
-				}
+		var over bool
+
+		game.condition.L.Lock()
+
+		switch message.Event {
+		case EventPlayMove:
+			over, err = game.PlayMove(playerColor, message.Move)
+		case EventResign:
+			over = game.Resign(playerColor)
+		case EventOfferDraw:
+			game.OfferDraw(playerColor)
+		case EventAcceptDraw:
+			over = game.AcceptDraw(playerColor)
+		case EventDeclineDraw:
+			game.DeclineDraw(playerColor)
+		case EventRematchOffer:
+			game.OfferRematch(playerColor)
+		case EventRematchAccept:
+			if err = h.state.AcceptRematch(game, playerColor); err != nil {
+				log.Println(err)
+				err = nil
+			}
+		case EventRematchDecline:
+			game.DeclineRematch(playerColor)
+		case EventChat:
+			game.Chat(playerColor, message.Chat)
+		}
 
-				_ = conn.Close()
-				_ = oppConn.Close()
+		game.condition.L.Unlock()
 
-				h.state.OnGameCompleted(gameId)
+		if over {
+			// the connection stays open, in case the players want a rematch
+			h.state.OnGameCompleted(gameId)
+		}
 
-				break
-			}
+		if err != nil {
+			goto wsWriteError
 		}
 	}
 
@@ -289,19 +382,30 @@ wsWriteError:
 
 func (hg *HostedGame) WatchForDisconnect(onGameComplete func()) {
 	for {
-		color := <-hg.onDisconnect
+		var color hivegame.HiveColor
 
-		var whenDisconnected time.Time
+		select {
+		case color = <-hg.onDisconnect:
+		case <-hg.done:
+			return
+		}
+
+		var whenDisconnected *time.Time
 
 		hg.disconnectMutex.Lock()
 		if color == hivegame.ColorBlack {
-			whenDisconnected = *hg.blackLastDisconnected
+			whenDisconnected = hg.blackLastDisconnected
 		} else {
-			whenDisconnected = *hg.whiteLastDisconnected
+			whenDisconnected = hg.whiteLastDisconnected
 		}
 		hg.disconnectMutex.Unlock()
 
-		toWait := DisconnectTimeout - time.Now().Sub(whenDisconnected)
+		if whenDisconnected == nil {
+			// the player came back before the disconnect was picked up
+			continue
+		}
+
+		toWait := DisconnectTimeout - time.Now().Sub(*whenDisconnected)
 
 		if toWait < 0 {
 			continue
@@ -309,7 +413,12 @@ func (hg *HostedGame) WatchForDisconnect(onGameComplete func()) {
 
 		var reconnectFailed bool
 
-		<-time.After(toWait)
+		select {
+		case <-time.After(toWait):
+		case <-hg.done:
+			return
+		}
+
 		hg.disconnectMutex.Lock()
 		var lastDisconnected *time.Time
 		if color == hivegame.ColorBlack {
@@ -326,33 +435,11 @@ func (hg *HostedGame) WatchForDisconnect(onGameComplete func()) {
 
 		if reconnectFailed {
 			hg.condition.L.Lock()
-			var err error
-			if color == hivegame.ColorBlack && hg.whiteConn != nil {
-				err = hg.whiteConn.WriteJSON(PlayMessage{
-					Event: EventGameCompleted,
-					Complete: &GameComplete{
-						Won: true,
-					},
-				})
-				hg.shutdown <- struct{}{}
-				_ = hg.whiteConn.Close()
+			if !hg.completed {
+				winner := opponentOf(color)
+				hg.Complete(&winner, ReasonDisconnected)
 				onGameComplete()
-			} else if color == hivegame.ColorWhite && hg.blackConn != nil {
-				err = hg.blackConn.WriteJSON(PlayMessage{
-					Event: EventGameCompleted,
-					Complete: &GameComplete{
-						Won: true,
-					},
-				})
-				hg.shutdown <- struct{}{}
-				_ = hg.blackConn.Close()
-				onGameComplete()
-			}
-
-			if err != nil {
-				log.Println("Error writing to websocket after disconnect", err)
 			}
-
 			hg.condition.L.Unlock()
 
 			return
@@ -361,10 +448,51 @@ func (hg *HostedGame) WatchForDisconnect(onGameComplete func()) {
 }
 
 func (state *HostedGameState) OnGameCompleted(id string) {
-	if _, ok := state.games.Load(id); !ok {
+	// only the caller that removes the game goes on to rate it and announce it
+	got, ok := state.games.LoadAndDelete(id)
+	if !ok {
 		log.Printf("OnGameCompleted() called with id %s and cannot be found in the map of current games", id)
 		return
 	}
 
-	state.games.Delete(id)
+	game, ok := got.(*HostedGame)
+	if !ok {
+		return
+	}
+
+	// the options never change, so can be read without the game's lock, which may be held here
+	if game.options.Public {
+		state.lobby.Publish(LobbyEvent{
+			Event:  LobbyEventGameFinished,
+			GameId: id,
+		})
+	}
+
+	state.rateGame(game)
+}
+
+// rateGame updates the ratings of the players of a completed game. The players and result of a
+// game no longer change once it is complete, so are read without the game's lock.
+func (state *HostedGameState) rateGame(game *HostedGame) {
+	result := game.result
+
+	if state.ratings == nil || result == nil || result.Reason == ReasonAbandoned ||
+		game.blackPlayer == 0 || game.whitePlayer == 0 || game.blackPlayer == game.whitePlayer {
+		return
+	}
+
+	blackScore := 0.5
+	if result.Winner != nil {
+		blackScore = 0
+		if *result.Winner == hivegame.ColorBlack {
+			blackScore = 1
+		}
+	}
+
+	category := RatingCategory(game.options.TimeControl)
+	err := state.ratings.RateGame(game.id, category, game.blackPlayer, game.whitePlayer, blackScore)
+
+	if err != nil {
+		log.Printf("Error rating game %s: %v\n", game.id, err)
+	}
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// RematchWindow is how long the players of a finished game stay connected, during which either can
+// offer the other a rematch
+const RematchWindow = 60 * time.Second
+
+// OfferRematch passes a rematch offer on to the opponent, so long as the game is over and they are
+// still connected. The caller must hold the game's lock.
+func (hg *HostedGame) OfferRematch(color hivegame.HiveColor) {
+	if !hg.completed || hg.result.Reason == ReasonAbandoned || hg.rematchOfferedBy != nil ||
+		hg.connOf(opponentOf(color)) == nil {
+		return
+	}
+
+	hg.rematchOfferedBy = &color
+
+	hg.sendOrLog(opponentOf(color), PlayMessage{
+		Event: EventRematchOffer,
+	})
+}
+
+// DeclineRematch turns down a rematch offered by the opponent of the given colour, which ends the
+// rematch window. The caller must hold the game's lock.
+func (hg *HostedGame) DeclineRematch(color hivegame.HiveColor) {
+	if !hg.rematchOfferedFor(color) {
+		return
+	}
+
+	hg.sendOrLog(opponentOf(color), PlayMessage{
+		Event: EventRematchDecline,
+	})
+
+	hg.closeConnections()
+}
+
+// AcceptRematch hosts a new game for the players of a finished one if the opponent of the given
+// colour has offered a rematch. The new game has the same options with the colours swapped, but is
+// kept out of the lobby and abandoned if the players do not both join within MatchJoinTimeout.
+// Each player is sent its id before their connection to the old game is closed. The caller must
+// hold the old game's lock.
+func (state *HostedGameState) AcceptRematch(hg *HostedGame, color hivegame.HiveColor) error {
+	if !hg.rematchOfferedFor(color) {
+		return nil
+	}
+
+	options := hg.options
+	options.BlackSeat = hg.whitePlayer
+	options.WhiteSeat = hg.blackPlayer
+	// both seats are taken, so there is nothing to list
+	options.Public = false
+
+	rematch, err := state.Host(options)
+	if err != nil {
+		return err
+	}
+
+	state.AbandonUnlessJoined(rematch, MatchJoinTimeout)
+
+	for _, c := range []hivegame.HiveColor{hivegame.ColorBlack, hivegame.ColorWhite} {
+		hg.sendOrLog(c, PlayMessage{
+			Event: EventRematch,
+			Rematch: &GameRematch{
+				GameId: rematch.id,
+				Color:  opponentOf(c),
+			},
+		})
+	}
+
+	hg.closeConnections()
+
+	return nil
+}
+
+// rematchOfferedFor is true if the player of the given colour has a rematch offer open to them
+func (hg *HostedGame) rematchOfferedFor(color hivegame.HiveColor) bool {
+	return hg.completed && hg.rematchOfferedBy != nil && *hg.rematchOfferedBy == opponentOf(color)
+}
+
+// closeConnections closes both players' connections to a finished game. The connections are
+// forgotten first, so that their readers do not take them closing as a player leaving. The caller
+// must hold the game's lock.
+func (hg *HostedGame) closeConnections() {
+	if hg.rematchTimer != nil {
+		hg.rematchTimer.Stop()
+	}
+
+	for _, conn := range []*websocket.Conn{hg.blackConn, hg.whiteConn} {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+
+	hg.blackConn = nil
+	hg.whiteConn = nil
+}
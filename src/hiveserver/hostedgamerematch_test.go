@@ -0,0 +1,70 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"testing"
+)
+
+func TestAcceptRematch(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore(), lobby: NewLobbyHub()}
+
+	game, err := state.Host(HostedGameOptions{Variant: DefaultVariant, Public: true, CreatorId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.condition.L.Lock()
+	defer game.condition.L.Unlock()
+
+	game.blackPlayer = 1
+	game.whitePlayer = 2
+
+	black := hivegame.ColorBlack
+	game.Complete(&black, ReasonResigned)
+
+	// nothing happens until a rematch is offered
+	if err = state.AcceptRematch(game, hivegame.ColorWhite); err != nil {
+		t.Fatal(err)
+	}
+
+	game.rematchOfferedBy = &black
+
+	// the player who offered cannot accept their own offer
+	if err = state.AcceptRematch(game, hivegame.ColorBlack); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	state.games.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+
+	if count != 1 {
+		t.Fatalf("A rematch was hosted before it was accepted")
+	}
+
+	if err = state.AcceptRematch(game, hivegame.ColorWhite); err != nil {
+		t.Fatal(err)
+	}
+
+	var rematch *HostedGame
+	state.games.Range(func(_, value any) bool {
+		if value.(*HostedGame) != game {
+			rematch = value.(*HostedGame)
+		}
+		return true
+	})
+
+	if rematch == nil {
+		t.Fatalf("No rematch was hosted")
+	}
+
+	if rematch.options.BlackSeat != 2 || rematch.options.WhiteSeat != 1 {
+		t.Fatalf("Colours were not swapped: black %d, white %d", rematch.options.BlackSeat, rematch.options.WhiteSeat)
+	}
+
+	if rematch.options.Public {
+		t.Fatalf("Rematch was listed in the lobby")
+	}
+}
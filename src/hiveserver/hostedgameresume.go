@@ -0,0 +1,163 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"log"
+	"time"
+)
+
+// ResumeGracePeriod is how long players have to come back to a game after the server restarts,
+// which is longer than DisconnectTimeout as their clients have to notice the restart first
+const ResumeGracePeriod = 2 * time.Minute
+
+// ResumeGames rebuilds every game left in progress in the store, as it was when the server last
+// saved it. Both players are treated as having disconnected when the server restarted.
+func (state *HostedGameState) ResumeGames() error {
+	records, err := state.store.InProgress()
+	if err != nil {
+		return err
+	}
+
+	resumed := 0
+
+	for _, record := range records {
+		game, ok := resumeHostedGame(record, state.store)
+
+		if !ok {
+			log.Printf("Could not replay the moves of game %s, so it has been abandoned\n", record.Id)
+
+			record.Result = &GameResult{Reason: ReasonAbandoned}
+			record.Updated = time.Now()
+
+			if err = state.store.Save(record); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		state.games.Store(record.Id, game)
+		resumed++
+
+		if game.blackPlayer == 0 || game.whitePlayer == 0 {
+			// the game had not started, so whoever joined can come back whenever
+			continue
+		}
+
+		id := record.Id
+		onGameComplete := func() {
+			state.OnGameCompleted(id)
+		}
+
+		// the clock waits for a player to come back, so that the grace period costs nobody time
+		game.condition.L.Lock()
+		game.onFlag = onGameComplete
+		game.condition.L.Unlock()
+
+		go game.WatchForResume(onGameComplete)
+	}
+
+	log.Printf("Resumed %d games\n", resumed)
+
+	return nil
+}
+
+// resumeHostedGame replays a record's moves into a new game. Returns false if any of them are no
+// longer legal.
+func resumeHostedGame(record GameRecord, store GameStore) (*HostedGame, bool) {
+	variant := record.Variant
+	if variant == "" {
+		variant = DefaultVariant
+	}
+
+	game := NewHostedGame(record.Id, HostedGameOptions{
+		TimeControl:    record.TimeControl,
+		SpectatorDelay: record.SpectatorDelay,
+		Public:         record.Public,
+		Variant:        variant,
+		CreatorId:      record.CreatorId,
+		CreatorName:    record.CreatorName,
+		BlackSeat:      record.BlackSeat,
+		WhiteSeat:      record.WhiteSeat,
+	}, store)
+
+	game.created = record.Created
+	game.blackPlayer = record.BlackPlayer
+	game.whitePlayer = record.WhitePlayer
+	game.chat = record.Chat
+
+	for i := range record.Moves {
+		if !record.Moves[i].valid() || !game.RecordMove(&record.Moves[i]) {
+			return nil, false
+		}
+	}
+
+	if game.clock != nil && record.Clock != nil {
+		game.clock.Restore(record.Clock)
+	}
+
+	// the delay was there to hide moves that are now in the past
+	game.spectatorGame = game.hiveGame.Clone()
+	game.spectatorMoves = append([]HiveMove(nil), game.moves...)
+	game.spectatorClock = game.clockState()
+
+	now := time.Now()
+	game.resumedAt = &now
+	game.blackLastDisconnected = &now
+	game.whiteLastDisconnected = &now
+
+	return game, true
+}
+
+// ResumeClock starts the clock of a resumed game when the first of its players comes back. The
+// caller must hold the game's lock.
+func (hg *HostedGame) ResumeClock() {
+	if hg.resumedAt == nil || hg.clock == nil || hg.clock.running || hg.completed {
+		return
+	}
+
+	hg.StartClock(hg.onFlag)
+}
+
+// WatchForResume gives both players of a resumed game ResumeGracePeriod to reconnect. A player who
+// does not loses, and the game is abandoned if neither does. Otherwise it watches for disconnects
+// as usual.
+func (hg *HostedGame) WatchForResume(onGameComplete func()) {
+	select {
+	case <-time.After(ResumeGracePeriod):
+	case <-hg.done:
+		return
+	}
+
+	hg.condition.L.Lock()
+
+	// a player who came back and then disconnected again is left to WatchForDisconnect
+	blackGone := !hg.blackReturned
+	whiteGone := !hg.whiteReturned
+
+	if !hg.completed && (blackGone || whiteGone) {
+		var winner *hivegame.HiveColor
+		reason := ReasonDisconnected
+
+		if blackGone && whiteGone {
+			reason = ReasonAbandoned
+		} else if blackGone {
+			white := hivegame.ColorWhite
+			winner = &white
+		} else {
+			black := hivegame.ColorBlack
+			winner = &black
+		}
+
+		hg.Complete(winner, reason)
+		onGameComplete()
+	}
+
+	completed := hg.completed
+
+	hg.condition.L.Unlock()
+
+	if !completed {
+		hg.WatchForDisconnect(onGameComplete)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+	"testing"
+	"time"
+)
+
+func resumedState(t *testing.T, records ...GameRecord) *HostedGameState {
+	state := &HostedGameState{store: NewMemoryGameStore()}
+
+	for _, record := range records {
+		if err := state.store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := state.ResumeGames(); err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
+
+func resumedGame(t *testing.T, state *HostedGameState, id string) *HostedGame {
+	got, ok := state.games.Load(id)
+	if !ok {
+		t.Fatalf("Game %s was not resumed", id)
+	}
+
+	return got.(*HostedGame)
+}
+
+func TestResumeGames(t *testing.T) {
+	state := resumedState(t, testRecord("LIVE"))
+	game := resumedGame(t, state, "LIVE")
+
+	game.condition.L.Lock()
+	defer game.condition.L.Unlock()
+
+	// stop WatchForResume from outliving the test
+	defer game.Complete(nil, ReasonAbandoned)
+
+	if len(game.moves) != 2 || game.hiveGame.Move != 2 || game.hiveGame.ColorToMove != hivegame.ColorBlack {
+		t.Fatalf("Moves were not replayed: %+v", game.moves)
+	}
+
+	if game.blackPlayer != 1 || game.whitePlayer != 2 {
+		t.Fatalf("Players were not restored: %d and %d", game.blackPlayer, game.whitePlayer)
+	}
+
+	if game.clock.running {
+		t.Fatalf("Clock started before either player came back")
+	}
+
+	clock := game.clockState()
+	if clock.BlackMs != 59000 || clock.WhiteMs != 58000 {
+		t.Fatalf("Clock was not restored: %+v", clock)
+	}
+
+	game.ResumeClock()
+
+	if !game.clock.running || game.clock.turn != hivegame.ColorBlack {
+		t.Fatalf("Clock did not start for black once a player came back")
+	}
+}
+
+func TestResumeAbandonsIllegalGames(t *testing.T) {
+	record := testRecord("BAD")
+	// white's first piece must touch black's
+	record.Moves[1] = placement(hivegame.PieceTypeQueenBee, 5, 5)
+
+	state := resumedState(t, record)
+
+	if _, ok := state.games.Load("BAD"); ok {
+		t.Fatalf("Resumed a game whose moves are not legal")
+	}
+
+	saved, _, _ := state.store.Load("BAD")
+	if saved.Result == nil || saved.Result.Reason != ReasonAbandoned {
+		t.Fatalf("Game was not abandoned: %+v", saved.Result)
+	}
+}
+
+func TestWatchForDisconnectSkipsPlayersWhoCameBack(t *testing.T) {
+	game := NewHostedGame("ABC", HostedGameOptions{}, NewMemoryGameStore())
+	watching := make(chan struct{})
+
+	go func() {
+		game.WatchForDisconnect(func() {})
+		close(watching)
+	}()
+
+	// the disconnect is still queued when the player reconnects and clears their timestamp
+	game.onDisconnect <- hivegame.ColorBlack
+	game.onDisconnect <- hivegame.ColorWhite
+	close(game.done)
+
+	select {
+	case <-watching:
+	case <-time.After(time.Second):
+		t.Fatalf("WatchForDisconnect did not stop")
+	}
+
+	if game.completed {
+		t.Fatalf("Game was completed although nobody is disconnected")
+	}
+}
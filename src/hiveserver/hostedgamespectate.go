@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+)
+
+type HostedGameSpectateHandler struct {
+	upgrader websocket.Upgrader
+	state    *HostedGameState
+}
+
+func CreateHostedGameSpectateHandler(hostedGameState *HostedGameState) *HostedGameSpectateHandler {
+	return &HostedGameSpectateHandler{
+		state: hostedGameState,
+	}
+}
+
+// ServeHTTP lets anyone watch a hosted game. Spectators are sent the game as it stands and then each
+// move and the result; anything they send is ignored.
+func (h *HostedGameSpectateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("GET %s?%s\n", r.URL.Path, r.URL.RawQuery)
+
+	gameId := r.URL.Query().Get("id")
+
+	got, ok := h.state.games.Load(gameId)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	game, ok := got.(*HostedGame)
+	if !ok {
+		log.Println("500 Could not cast games map value to *HostedGame")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Error upgrading connection to websocket", err)
+		return
+	}
+
+	game.condition.L.Lock()
+	added, err := game.AddSpectator(conn)
+	game.condition.L.Unlock()
+
+	if err != nil {
+		log.Println("Error writing to websocket", err)
+	}
+
+	if !added || err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	game.condition.L.Lock()
+	game.RemoveSpectator(conn)
+	game.condition.L.Unlock()
+
+	_ = conn.Close()
+}
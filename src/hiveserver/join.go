@@ -2,39 +2,34 @@ package main
 
 import (
 	"encoding/json"
-	"github.com/golang-jwt/jwt/v5"
 	"log"
 	"net/http"
-	"os"
-	"sync/atomic"
 )
 
 type joinHandler struct {
-	previousPlayerId atomic.Uint64
+	playerIds *PlayerIdAllocator
+	sessions  *SessionStore
 }
 
-type joinResponse struct {
-	Token string `json:"token"`
-}
+func (h *joinHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	playerId, err := h.playerIds.Next()
 
-func (h *joinHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
-	response := joinResponse{}
-	playerId := h.previousPlayerId.Add(1)
+	if err != nil {
+		log.Printf("Error allocating a player id: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	log.Printf("GET /join, created player id = %d\n", playerId)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": playerId})
-	secretString := os.Getenv("JWT_SECRET")
-	tokenString, err := token.SignedString([]byte(secretString))
+	response, err := startSession(r, h.sessions, playerId)
 
 	if err != nil {
-		log.Printf("Error signing token: %v\n", err)
+		log.Printf("Error starting session: %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	response.Token = tokenString
-
 	err = json.NewEncoder(w).Encode(response)
 
 	if err != nil {
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LobbyHistorySize is how many events are kept for clients resuming with Last-Event-ID. A client
+	// that has missed more than this is sent a fresh snapshot instead.
+	LobbyHistorySize = 256
+	// lobbySubscriberBuffer is how far a client can fall behind before it is dropped, to reconnect
+	// and resume where it left off
+	lobbySubscriberBuffer = 64
+	lobbyKeepAlive        = 25 * time.Second
+	// lobbyOnlineInterval is the most often the online count is sent
+	lobbyOnlineInterval = 2 * time.Second
+)
+
+const (
+	// LobbyEventSnapshot is sent first to a client that cannot resume, with every open public game
+	LobbyEventSnapshot = "SNAPSHOT"
+	// LobbyEventGameCreated is sent when a public game is created, with its listing
+	LobbyEventGameCreated = "GAME_CREATED"
+	// LobbyEventGameJoined is sent when the first player takes a seat in a public game
+	LobbyEventGameJoined = "GAME_JOINED"
+	// LobbyEventGameStarted is sent when a public game has both its players, and so leaves the list
+	LobbyEventGameStarted  = "GAME_STARTED"
+	LobbyEventGameFinished = "GAME_FINISHED"
+	// LobbyEventOnline is sent with the number of players connected, when it changes
+	LobbyEventOnline = "ONLINE"
+)
+
+type LobbyEvent struct {
+	Id    uint64 `json:"-"`
+	Event string `json:"-"`
+	// Game is set for created and joined games
+	Game *HostedGameListing `json:"game,omitempty"`
+	// GameId is set for started and finished games
+	GameId string `json:"gameId,omitempty"`
+	// Games is set for snapshots
+	Games  []HostedGameListing `json:"games,omitempty"`
+	Online *int                `json:"online,omitempty"`
+}
+
+// LobbyHub passes lobby events on to every client subscribed to them, and keeps the most recent
+// ones for clients that reconnect
+type LobbyHub struct {
+	mutex sync.Mutex
+	// epoch tells this process's event ids apart from those of earlier ones, which start from 1 too
+	epoch  int64
+	lastId uint64
+	// history is a ring buffer of the last LobbyHistorySize events
+	history     [LobbyHistorySize]LobbyEvent
+	subscribers map[chan LobbyEvent]bool
+	// players connected to a game, and clients watching the lobby
+	players      int
+	watchers     int
+	onlineQueued bool
+}
+
+func NewLobbyHub() *LobbyHub {
+	return &LobbyHub{
+		epoch:       time.Now().UnixNano(),
+		subscribers: make(map[chan LobbyEvent]bool),
+	}
+}
+
+// EventId is the id sent to clients for the event numbered id, as <epoch>-<id>
+func (hub *LobbyHub) EventId(id uint64) string {
+	return fmt.Sprintf("%d-%d", hub.epoch, id)
+}
+
+// ParseEventId returns the number of an event id from EventId, or false if it is malformed or was
+// sent before the server last restarted
+func (hub *LobbyHub) ParseEventId(given string) (uint64, bool) {
+	epoch, id, found := strings.Cut(given, "-")
+	if !found || epoch != strconv.FormatInt(hub.epoch, 10) {
+		return 0, false
+	}
+
+	lastId, err := strconv.ParseUint(id, 10, 64)
+
+	return lastId, err == nil
+}
+
+func (hub *LobbyHub) Publish(event LobbyEvent) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.publish(event)
+}
+
+// publish must be called while holding the hub's mutex
+func (hub *LobbyHub) publish(event LobbyEvent) {
+	hub.lastId++
+	event.Id = hub.lastId
+	hub.history[event.Id%LobbyHistorySize] = event
+
+	for subscriber := range hub.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// the client is too far behind; closing the channel ends its stream
+			delete(hub.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Subscribe returns a channel of every event after lastId, or of every event from now on along
+// with false if the events after lastId are no longer all kept
+func (hub *LobbyHub) Subscribe(lastId uint64, resuming bool) (chan LobbyEvent, uint64, bool) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	subscriber := make(chan LobbyEvent, lobbySubscriberBuffer+LobbyHistorySize)
+
+	resumable := resuming && lastId <= hub.lastId && hub.lastId-lastId <= LobbyHistorySize
+	if resumable {
+		for id := lastId + 1; id <= hub.lastId; id++ {
+			subscriber <- hub.history[id%LobbyHistorySize]
+		}
+	}
+
+	hub.subscribers[subscriber] = true
+	hub.watchers++
+	hub.onlineChanged()
+
+	return subscriber, hub.lastId, resumable
+}
+
+func (hub *LobbyHub) Unsubscribe(subscriber chan LobbyEvent) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.subscribers[subscriber] {
+		delete(hub.subscribers, subscriber)
+		close(subscriber)
+	}
+
+	hub.watchers--
+	hub.onlineChanged()
+}
+
+func (hub *LobbyHub) PlayerConnected() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.players++
+	hub.onlineChanged()
+}
+
+func (hub *LobbyHub) PlayerDisconnected() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.players--
+	hub.onlineChanged()
+}
+
+func (hub *LobbyHub) Online() int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	return hub.players + hub.watchers
+}
+
+// onlineChanged sends the online count after lobbyOnlineInterval, so that a burst of connections
+// is only sent once. It must be called while holding the hub's mutex.
+func (hub *LobbyHub) onlineChanged() {
+	if hub.onlineQueued {
+		return
+	}
+
+	hub.onlineQueued = true
+
+	time.AfterFunc(lobbyOnlineInterval, func() {
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+
+		hub.onlineQueued = false
+		online := hub.players + hub.watchers
+
+		hub.publish(LobbyEvent{
+			Event:  LobbyEventOnline,
+			Online: &online,
+		})
+	})
+}
+
+type LobbyEventsHandler struct {
+	state *HostedGameState
+}
+
+func CreateLobbyEventsHandler(hostedGameState *HostedGameState) *LobbyEventsHandler {
+	return &LobbyEventsHandler{
+		state: hostedGameState,
+	}
+}
+
+// ServeHTTP streams lobby events. A client reconnecting with Last-Event-ID, or a lastEventId query
+// parameter, carries on from where it left off; any other client is first sent a snapshot.
+func (h *LobbyEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("GET %s\n", r.URL.Path)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	given := r.Header.Get("Last-Event-ID")
+	if given == "" {
+		given = r.URL.Query().Get("lastEventId")
+	}
+
+	lobby := h.state.lobby
+	lastId, resuming := lobby.ParseEventId(given)
+	subscriber, lastId, resumable := lobby.Subscribe(lastId, resuming)
+	defer lobby.Unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumable {
+		online := lobby.Online()
+
+		// later events are already queued, and may repeat what the snapshot shows
+		err := writeLobbyEvent(w, lobby, LobbyEvent{
+			Id:     lastId,
+			Event:  LobbyEventSnapshot,
+			Games:  h.state.OpenListings(),
+			Online: &online,
+		})
+
+		if err != nil {
+			return
+		}
+	}
+
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(lobbyKeepAlive)
+	defer keepAlive.Stop()
+
+	var err error
+
+	for {
+		select {
+		case event, open := <-subscriber:
+			if !open {
+				return
+			}
+
+			err = writeLobbyEvent(w, lobby, event)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeLobbyEvent(w http.ResponseWriter, hub *LobbyHub, event LobbyEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", hub.EventId(event.Id), event.Event, data)
+
+	return err
+}
+
+// OpenListings lists every open public game, newest first
+func (state *HostedGameState) OpenListings() []HostedGameListing {
+	listings := make([]HostedGameListing, 0)
+
+	state.games.Range(func(_, value any) bool {
+		game, ok := value.(*HostedGame)
+		if !ok {
+			return true
+		}
+
+		game.condition.L.Lock()
+		listing, open := game.Listing()
+		game.condition.L.Unlock()
+
+		if open {
+			listings = append(listings, listing)
+		}
+
+		return true
+	})
+
+	slices.SortFunc(listings, func(a, b HostedGameListing) int {
+		return b.Created.Compare(a.Created)
+	})
+
+	return listings
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// firstLobbyEvent opens the lobby stream with the given Last-Event-ID, and returns the first event
+// sent on it along with its data
+func firstLobbyEvent(t *testing.T, url, lastEventId string) (string, string) {
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("Last-Event-ID", lastEventId)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var event, data string
+	reader := bufio.NewReader(response.Body)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event, data
+		}
+
+		key, value, _ := strings.Cut(line, ": ")
+		switch key {
+		case "event":
+			event = value
+		case "data":
+			data = value
+		}
+	}
+}
+
+func TestLobbyResumesOnlyWithinEpoch(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore(), lobby: NewLobbyHub()}
+
+	game, err := state.Host(HostedGameOptions{Variant: DefaultVariant, Public: true, CreatorId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(CreateLobbyEventsHandler(state))
+	defer server.Close()
+
+	event, _ := firstLobbyEvent(t, server.URL, state.lobby.EventId(0))
+	if event != LobbyEventGameCreated {
+		t.Fatalf("Resuming from this epoch sent %s rather than the missed event", event)
+	}
+
+	// the same number from before a restart refers to a different event
+	previous := fmt.Sprintf("%d-%d", state.lobby.epoch-1, 1)
+
+	event, data := firstLobbyEvent(t, server.URL, previous)
+	if event != LobbyEventSnapshot || !strings.Contains(data, game.id) {
+		t.Fatalf("An id from a previous epoch was sent %s %s rather than the full listing", event, data)
+	}
+
+	for _, given := range []string{"", "1", "-1", state.lobby.EventId(0) + "x"} {
+		if _, resuming := state.lobby.ParseEventId(given); resuming {
+			t.Errorf("Malformed event id %q was resumed from", given)
+		}
+	}
+}
+
+func TestGameFinishedPublishedOnce(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore(), lobby: NewLobbyHub()}
+
+	game, err := state.Host(HostedGameOptions{Variant: DefaultVariant, Public: true, CreatorId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber, _, _ := state.lobby.Subscribe(0, false)
+	defer state.lobby.Unsubscribe(subscriber)
+
+	var completing sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		completing.Add(1)
+		go func() {
+			defer completing.Done()
+			state.OnGameCompleted(game.id)
+		}()
+	}
+	completing.Wait()
+
+	finished := 0
+	for len(subscriber) > 0 {
+		if event := <-subscriber; event.Event == LobbyEventGameFinished {
+			finished++
+		}
+	}
+
+	if finished != 1 {
+		t.Fatalf("Game finished was published %d times", finished)
+	}
+}
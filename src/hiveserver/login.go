@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MinPasswordLength    = 8
+	MaxPasswordLength    = 128
+	MaxDisplayNameLength = 32
+	// maxCredentialsBody is far more than any valid registration needs
+	maxCredentialsBody = 4096
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_]{3,20}$`)
+
+// dummyPasswordHash is checked against when logging in as a username that does not exist, so that
+// the response takes as long as for one that does
+var dummyPasswordHash, _ = HashPassword("dummy password")
+
+type registerHandler struct {
+	accounts  *AccountStore
+	playerIds *PlayerIdAllocator
+	sessions  *SessionStore
+	throttle  *LoginThrottle
+}
+
+type loginHandler struct {
+	accounts *AccountStore
+	sessions *SessionStore
+	throttle *LoginThrottle
+}
+
+type credentials struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"displayName"`
+}
+
+type accountResponse struct {
+	tokenResponse
+	Id          uint64 `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+}
+
+func (h *registerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /register")
+
+	var given credentials
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCredentialsBody)
+	if err := json.NewDecoder(r.Body).Decode(&given); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	username := NormalizeUsername(given.Username)
+	displayName := given.DisplayName
+	if displayName == "" {
+		displayName = given.Username
+	}
+
+	if !usernamePattern.MatchString(username) ||
+		len(given.Password) < MinPasswordLength || len(given.Password) > MaxPasswordLength ||
+		!utf8.ValidString(displayName) || utf8.RuneCountInString(displayName) > MaxDisplayNameLength {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, taken := h.accounts.ByUsername(username); taken {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	if !h.throttle.RegistrationAllowed(remoteAddress(r), time.Now()) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	passwordHash, err := HashPassword(given.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	playerId, err := h.playerIds.Next()
+	if err != nil {
+		log.Printf("Error allocating a player id: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	account := Account{
+		Id:           playerId,
+		Username:     username,
+		DisplayName:  displayName,
+		PasswordHash: passwordHash,
+		Created:      time.Now(),
+	}
+
+	err = h.accounts.Create(account)
+	if errors.Is(err, ErrUsernameTaken) {
+		// someone else registered the name while the password was hashing
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error saving account: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Registered %s with player id = %d\n", username, playerId)
+
+	writeAccountResponse(w, r, h.sessions, account)
+}
+
+func (h *loginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /login")
+
+	var given credentials
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCredentialsBody)
+	if err := json.NewDecoder(r.Body).Decode(&given); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	username := NormalizeUsername(given.Username)
+	address := remoteAddress(r)
+
+	if !h.throttle.Allowed(username, address, time.Now()) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	account, ok := h.accounts.ByUsername(username)
+
+	if !ok {
+		CheckPassword(given.Password, dummyPasswordHash)
+		h.throttle.Failed(username, address, time.Now())
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !CheckPassword(given.Password, account.PasswordHash) {
+		h.throttle.Failed(username, address, time.Now())
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.throttle.Succeeded(username)
+
+	writeAccountResponse(w, r, h.sessions, account)
+}
+
+// writeAccountResponse signs the player in to their account on a new session
+func writeAccountResponse(w http.ResponseWriter, r *http.Request, sessions *SessionStore, account Account) {
+	tokens, err := startSession(r, sessions, account.Id)
+
+	if err != nil {
+		log.Printf("Error starting session: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(accountResponse{
+		tokenResponse: tokens,
+		Id:            account.Id,
+		Username:      account.Username,
+		DisplayName:   account.DisplayName,
+	})
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// LoginWindow is how long a failed login counts against the account and the address it came
+	// from
+	LoginWindow = 15 * time.Minute
+	// MaxAccountFailures failed logins to one account within LoginWindow lock it out until the
+	// oldest of them has passed
+	MaxAccountFailures = 5
+	// MaxAddressFailures is higher than MaxAccountFailures as players can share an address
+	MaxAddressFailures = 20
+	// MaxAddressRegistrations is how many registrations an address can attempt within LoginWindow,
+	// as each of them hashes a password
+	MaxAddressRegistrations = 10
+)
+
+// loginFailures counts the failed logins since the first of them
+type loginFailures struct {
+	count int
+	since time.Time
+}
+
+// LoginThrottle locks out an account, or an address, after too many failed logins in a row, so
+// that passwords cannot be guessed at the rate the server can check them. It also limits how often
+// an address can register, so that registering cannot be used to keep the server hashing.
+type LoginThrottle struct {
+	mutex     sync.Mutex
+	accounts  map[string]*loginFailures
+	addresses map[string]*loginFailures
+	// registration attempts by address, counted the same way as failures
+	registrations map[string]*loginFailures
+	// failures older than LoginWindow are swept out at most once per window
+	lastSwept time.Time
+}
+
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		accounts:      make(map[string]*loginFailures),
+		addresses:     make(map[string]*loginFailures),
+		registrations: make(map[string]*loginFailures),
+	}
+}
+
+// Allowed is false if the username or the address is locked out
+func (throttle *LoginThrottle) Allowed(username, address string, now time.Time) bool {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	return failuresBelow(throttle.accounts[username], MaxAccountFailures, now) &&
+		failuresBelow(throttle.addresses[address], MaxAddressFailures, now)
+}
+
+// Failed counts a failed login against the username and the address
+func (throttle *LoginThrottle) Failed(username, address string, now time.Time) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	throttle.sweep(now)
+	addFailure(throttle.accounts, username, now)
+	addFailure(throttle.addresses, address, now)
+}
+
+// RegistrationAllowed is false if the address has attempted too many registrations, and otherwise
+// counts this one against it
+func (throttle *LoginThrottle) RegistrationAllowed(address string, now time.Time) bool {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	if !failuresBelow(throttle.registrations[address], MaxAddressRegistrations, now) {
+		return false
+	}
+
+	throttle.sweep(now)
+	addFailure(throttle.registrations, address, now)
+
+	return true
+}
+
+// Succeeded clears the failures against the username, though not those against the address
+func (throttle *LoginThrottle) Succeeded(username string) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	delete(throttle.accounts, username)
+}
+
+// sweep removes counts older than LoginWindow, at most once per window
+func (throttle *LoginThrottle) sweep(now time.Time) {
+	if now.Sub(throttle.lastSwept) <= LoginWindow {
+		return
+	}
+
+	for _, failures := range []map[string]*loginFailures{throttle.accounts, throttle.addresses, throttle.registrations} {
+		for key, failure := range failures {
+			if now.Sub(failure.since) > LoginWindow {
+				delete(failures, key)
+			}
+		}
+	}
+
+	throttle.lastSwept = now
+}
+
+func failuresBelow(failures *loginFailures, limit int, now time.Time) bool {
+	return failures == nil || failures.count < limit || now.Sub(failures.since) > LoginWindow
+}
+
+func addFailure(failures map[string]*loginFailures, key string, now time.Time) {
+	failure, ok := failures[key]
+
+	if !ok || now.Sub(failure.since) > LoginWindow {
+		failures[key] = &loginFailures{count: 1, since: now}
+		return
+	}
+
+	failure.count++
+}
+
+// remoteAddress is the address a request came from, without its port
+func remoteAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
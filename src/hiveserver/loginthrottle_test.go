@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleLocksOutAccount(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < MaxAccountFailures; i++ {
+		if !throttle.Allowed("alice", "10.0.0.1", now) {
+			t.Fatalf("Locked out after %d failures", i)
+		}
+
+		throttle.Failed("alice", "10.0.0.1", now)
+	}
+
+	if throttle.Allowed("alice", "10.0.0.2", now) {
+		t.Fatalf("Account was not locked out")
+	}
+
+	if !throttle.Allowed("bob", "10.0.0.1", now) {
+		t.Fatalf("Address was locked out along with the account")
+	}
+
+	if !throttle.Allowed("alice", "10.0.0.2", now.Add(LoginWindow+time.Second)) {
+		t.Fatalf("Account was still locked out after the window")
+	}
+}
+
+func TestLoginThrottleLocksOutAddress(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < MaxAddressFailures; i++ {
+		// a different account each time, so that none of them is locked out
+		throttle.Failed(string(rune('a'+i)), "10.0.0.1", now)
+	}
+
+	if throttle.Allowed("zebra", "10.0.0.1", now) {
+		t.Fatalf("Address was not locked out")
+	}
+}
+
+func TestLoginThrottleClearsAccountOnSuccess(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < MaxAccountFailures-1; i++ {
+		throttle.Failed("alice", "10.0.0.1", now)
+	}
+
+	throttle.Succeeded("alice")
+	throttle.Failed("alice", "10.0.0.1", now)
+
+	if !throttle.Allowed("alice", "10.0.0.1", now) {
+		t.Fatalf("Failures before a successful login still count")
+	}
+}
+
+func TestLoginThrottleSweepsOldFailures(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	throttle.Failed("alice", "10.0.0.1", now)
+	throttle.Failed("bob", "10.0.0.2", now.Add(2*LoginWindow))
+
+	if len(throttle.accounts) != 1 || len(throttle.addresses) != 1 {
+		t.Fatalf("Old failures were not swept: %d accounts and %d addresses", len(throttle.accounts),
+			len(throttle.addresses))
+	}
+}
+
+func TestLoginThrottleLimitsRegistrations(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < MaxAddressRegistrations; i++ {
+		if !throttle.RegistrationAllowed("10.0.0.1", now) {
+			t.Fatalf("Registration refused after %d attempts", i)
+		}
+	}
+
+	if throttle.RegistrationAllowed("10.0.0.1", now) {
+		t.Fatalf("Address was not limited")
+	}
+
+	if !throttle.RegistrationAllowed("10.0.0.2", now) {
+		t.Fatalf("Another address was limited")
+	}
+
+	if !throttle.Allowed("alice", "10.0.0.1", now) {
+		t.Fatalf("Registrations locked out logins")
+	}
+
+	if !throttle.RegistrationAllowed("10.0.0.1", now.Add(LoginWindow+time.Second)) {
+		t.Fatalf("Address was still limited after the window")
+	}
+}
@@ -25,6 +25,17 @@ func withHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// preflight answers a CORS preflight request for an endpoint that takes the given headers
+func preflight(allowHeaders string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("OPTIONS %s", r.URL.Path)
+		w.Header().Set("Access-Control-Allow-Origin", os.Getenv("CORS_ORIGIN"))
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+	}
+}
+
 func createServer() *http.Server {
 	var useTls bool
 	var development bool
@@ -40,7 +51,68 @@ func createServer() *http.Server {
 		fmt.Printf("Using CORS origin %s\n", os.Getenv("CORS_ORIGIN"))
 	}
 
+	store, err := NewGameStore()
+	if err != nil {
+		log.Fatalf("Error opening the game store:\n%v\n", err)
+	}
+
+	playerIds, err := OpenPlayerIdAllocator(os.Getenv("PLAYER_ID_PATH"))
+	if err != nil {
+		log.Fatalf("Error opening the player id file:\n%v\n", err)
+	}
+
+	if !playerIds.Persistent() {
+		log.Println("PLAYER_ID_PATH is not set, so tokens from before this start will be rejected")
+	}
+
+	if os.Getenv("GAME_STORE_PATH") != "" && !playerIds.Persistent() {
+		// resumed games would be handed to whoever is given their players' ids after a restart
+		log.Fatalln("GAME_STORE_PATH cannot be set without PLAYER_ID_PATH")
+	}
+
+	if _, _, err = signingKeys(); err != nil {
+		log.Fatalf("Error reading the JWT signing keys:\n%v\n", err)
+	}
+
+	if err = SetTokenEpoch(playerIds.Persistent()); err != nil {
+		log.Fatalf("Error reading JWT_EPOCH:\n%v\n", err)
+	}
+
+	accounts, err := OpenAccountStore(os.Getenv("ACCOUNT_STORE_PATH"))
+	if err != nil {
+		log.Fatalf("Error opening the account store:\n%v\n", err)
+	}
+
+	if os.Getenv("ACCOUNT_STORE_PATH") != "" && !playerIds.Persistent() {
+		// accounts would keep ids that are handed out again after a restart
+		log.Fatalln("ACCOUNT_STORE_PATH cannot be set without PLAYER_ID_PATH")
+	}
+
+	sessions, err := OpenSessionStore(os.Getenv("SESSION_STORE_PATH"))
+	if err != nil {
+		log.Fatalf("Error opening the session store:\n%v\n", err)
+	}
+
+	ratings, err := OpenRatingStore(os.Getenv("RATING_STORE_PATH"))
+	if err != nil {
+		log.Fatalf("Error opening the rating store:\n%v\n", err)
+	}
+
+	if os.Getenv("RATING_STORE_PATH") != "" && !playerIds.Persistent() {
+		// ratings would be carried over to whoever is handed the same id after a restart
+		log.Fatalln("RATING_STORE_PATH cannot be set without PLAYER_ID_PATH")
+	}
+
 	state = new(ServerState)
+	state.hostedGameState.store = store
+	state.hostedGameState.sessions = sessions
+	state.hostedGameState.accounts = accounts
+	state.hostedGameState.lobby = NewLobbyHub()
+	state.hostedGameState.ratings = ratings
+
+	if err = state.hostedGameState.ResumeGames(); err != nil {
+		log.Fatalf("Error resuming games:\n%v\n", err)
+	}
 
 	port := os.Getenv("PORT")
 
@@ -48,17 +120,33 @@ func createServer() *http.Server {
 		port = "8080"
 	}
 
+	matchmaker := NewMatchmaker(&state.hostedGameState)
+	throttle := NewLoginThrottle()
+
 	mux := http.NewServeMux()
 
-	mux.Handle("GET /join", withHeaders(new(joinHandler)))
+	mux.Handle("GET /join", withHeaders(&joinHandler{playerIds: playerIds, sessions: sessions}))
+	mux.Handle("POST /register", withHeaders(&registerHandler{accounts: accounts, playerIds: playerIds, sessions: sessions, throttle: throttle}))
+	mux.Handle("POST /login", withHeaders(&loginHandler{accounts: accounts, sessions: sessions, throttle: throttle}))
+	mux.Handle("POST /token/refresh", withHeaders(&refreshHandler{sessions: sessions}))
+	mux.Handle("POST /logout", withHeaders(&logoutHandler{sessions: sessions}))
+	mux.Handle("GET /sessions", withHeaders(&sessionsHandler{sessions: sessions}))
 	mux.Handle("GET /hosted-game/new", withHeaders(CreateHostedGameNewHandler(&state.hostedGameState)))
 	mux.Handle("GET /hosted-game/play", withHeaders(CreateHostedGamePlayHandler(&state.hostedGameState)))
-	mux.HandleFunc("OPTIONS /hosted-game/new", func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("OPTIONS %s", r.URL.Path)
-		w.Header().Set("Access-Control-Allow-Origin", os.Getenv("CORS_ORIGIN"))
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
-	})
+	mux.Handle("GET /hosted-game/list", withHeaders(CreateHostedGameListHandler(&state.hostedGameState)))
+	mux.Handle("GET /lobby/events", withHeaders(CreateLobbyEventsHandler(&state.hostedGameState)))
+	mux.Handle("GET /hosted-game/spectate", withHeaders(CreateHostedGameSpectateHandler(&state.hostedGameState)))
+	mux.Handle("POST /matchmaking/seek", withHeaders(&seekHandler{matchmaker: matchmaker, sessions: sessions}))
+	mux.Handle("DELETE /matchmaking/seek", withHeaders(&cancelSeekHandler{matchmaker: matchmaker, sessions: sessions}))
+	mux.Handle("GET /players/{id}/rating", withHeaders(&playerRatingHandler{state: &state.hostedGameState}))
+	mux.Handle("GET /leaderboard", withHeaders(&leaderboardHandler{state: &state.hostedGameState}))
+	mux.HandleFunc("OPTIONS /hosted-game/new", preflight("Authorization"))
+	mux.HandleFunc("OPTIONS /register", preflight("Content-Type"))
+	mux.HandleFunc("OPTIONS /login", preflight("Content-Type"))
+	mux.HandleFunc("OPTIONS /token/refresh", preflight("Content-Type"))
+	mux.HandleFunc("OPTIONS /logout", preflight("Authorization, Content-Type"))
+	mux.HandleFunc("OPTIONS /sessions", preflight("Authorization"))
+	mux.HandleFunc("OPTIONS /matchmaking/seek", preflight("Authorization, Content-Type"))
 
 	mux.Handle("/", http.FileServer(SpaFileServer(http.Dir("./static/"))))
 
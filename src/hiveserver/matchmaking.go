@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// SeekWait is how long a seek request is held open waiting for an opponent. The seek stays
+	// queued after it times out only if the player seeks again.
+	SeekWait = 30 * time.Second
+	// MatchJoinTimeout is how long both players of a matched game, or of a rematch, have to join it
+	// before it is abandoned
+	MatchJoinTimeout = time.Minute
+	// maxSeekBody is far more than any valid seek needs
+	maxSeekBody = 1024
+)
+
+// Seek is a player waiting in the matchmaking queue for an opponent
+type Seek struct {
+	PlayerId    uint64
+	Variant     string
+	TimeControl *TimeControl
+	// MinRating and MaxRating bound the opponent's rating; zero leaves that end open
+	MinRating float64
+	MaxRating float64
+	// Rating is the seeking player's own rating in the seek's time control
+	Rating float64
+	// matched is sent the game once an opponent is found, and closed if the seek is replaced
+	matched chan SeekMatch
+}
+
+// SeekMatch tells a player which game they have been paired into, and which colour is theirs
+type SeekMatch struct {
+	GameId string `json:"gameId"`
+	Color  string `json:"color"`
+}
+
+// Matchmaker pairs seeks that are compatible with each other, oldest first, and hosts a game for
+// each pair with its colours already handed out
+type Matchmaker struct {
+	mutex sync.Mutex
+	seeks []*Seek
+	state *HostedGameState
+}
+
+func NewMatchmaker(hostedGameState *HostedGameState) *Matchmaker {
+	return &Matchmaker{
+		state: hostedGameState,
+	}
+}
+
+// Accepts is true if an opponent with the given rating is inside the seek's range
+func (seek *Seek) Accepts(rating float64) bool {
+	return (seek.MinRating == 0 || rating >= seek.MinRating) && (seek.MaxRating == 0 || rating <= seek.MaxRating)
+}
+
+func (seek *Seek) compatible(other *Seek) bool {
+	return seek.PlayerId != other.PlayerId &&
+		seek.Variant == other.Variant &&
+		equalTimeControls(seek.TimeControl, other.TimeControl) &&
+		seek.Accepts(other.Rating) && other.Accepts(seek.Rating)
+}
+
+func equalTimeControls(a, b *TimeControl) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// Seek pairs the seek with the oldest compatible one in the queue, or else queues it in place of
+// any the player already has. The returned channel is sent the player's game once they are paired.
+func (mm *Matchmaker) Seek(seek *Seek) (<-chan SeekMatch, error) {
+	seek.Rating = DefaultRating
+	if mm.state.ratings != nil {
+		seek.Rating = mm.state.ratings.Rating(seek.PlayerId, RatingCategory(seek.TimeControl)).Rating
+	}
+	seek.matched = make(chan SeekMatch, 1)
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	mm.remove(seek.PlayerId)
+
+	index := slices.IndexFunc(mm.seeks, seek.compatible)
+	if index < 0 {
+		mm.seeks = append(mm.seeks, seek)
+		return seek.matched, nil
+	}
+
+	opponent := mm.seeks[index]
+
+	options := HostedGameOptions{
+		TimeControl: seek.TimeControl,
+		Variant:     seek.Variant,
+		CreatorName: "Matchmaking",
+		BlackSeat:   seek.PlayerId,
+		WhiteSeat:   opponent.PlayerId,
+	}
+
+	if rand.Intn(2) == 0 {
+		options.BlackSeat, options.WhiteSeat = options.WhiteSeat, options.BlackSeat
+	}
+
+	game, err := mm.state.Host(options)
+	if err != nil {
+		return nil, err
+	}
+
+	mm.seeks = slices.Delete(mm.seeks, index, index+1)
+	mm.state.AbandonUnlessJoined(game, MatchJoinTimeout)
+
+	for _, paired := range []*Seek{seek, opponent} {
+		color := ColorChoiceWhite
+		if paired.PlayerId == options.BlackSeat {
+			color = ColorChoiceBlack
+		}
+
+		paired.matched <- SeekMatch{GameId: game.id, Color: color}
+	}
+
+	log.Printf("Matched players %d and %d in game %s\n", seek.PlayerId, opponent.PlayerId, game.id)
+
+	return seek.matched, nil
+}
+
+// Cancel takes the player's seek out of the queue. Returns false if they have none, which may be
+// because it was just paired.
+func (mm *Matchmaker) Cancel(playerId uint64) bool {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	return mm.remove(playerId)
+}
+
+// remove must be called while holding the matchmaker's mutex
+func (mm *Matchmaker) remove(playerId uint64) bool {
+	index := slices.IndexFunc(mm.seeks, func(seek *Seek) bool {
+		return seek.PlayerId == playerId
+	})
+
+	if index < 0 {
+		return false
+	}
+
+	close(mm.seeks[index].matched)
+	mm.seeks = slices.Delete(mm.seeks, index, index+1)
+
+	return true
+}
+
+type seekHandler struct {
+	matchmaker *Matchmaker
+	sessions   *SessionStore
+}
+
+type cancelSeekHandler struct {
+	matchmaker *Matchmaker
+	sessions   *SessionStore
+}
+
+type seekRequest struct {
+	Variant   string  `json:"variant"`
+	Clock     string  `json:"clock"`
+	Initial   int     `json:"initial"`
+	Increment int     `json:"increment"`
+	MinRating float64 `json:"minRating"`
+	MaxRating float64 `json:"maxRating"`
+}
+
+// ServeHTTP queues a seek and waits for it to be paired. Responds with the game once it is, with
+// no content if SeekWait passes first, or with a conflict if the seek was replaced by another
+// from the same player.
+func (h *seekHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /matchmaking/seek")
+
+	claims, err := AuthenticateRequest(r, h.sessions)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var given seekRequest
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSeekBody)
+	if err = json.NewDecoder(r.Body).Decode(&given); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	seek, err := given.seek(claims.PlayerId)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	matched, err := h.matchmaker.Seek(seek)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	timeout := time.NewTimer(SeekWait)
+	defer timeout.Stop()
+
+	var match SeekMatch
+	var open bool
+
+	select {
+	case match, open = <-matched:
+	case <-timeout.C:
+		if h.matchmaker.Cancel(claims.PlayerId) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// the seek was paired or replaced as the wait ran out
+		match, open = <-matched
+	case <-r.Context().Done():
+		if !h.matchmaker.Cancel(claims.PlayerId) {
+			match, open = <-matched
+			if open {
+				log.Printf("Player %d left after being matched into game %s\n", claims.PlayerId, match.GameId)
+			}
+		}
+
+		return
+	}
+
+	if !open {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(match)
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
+
+func (h *cancelSeekHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("DELETE /matchmaking/seek")
+
+	claims, err := AuthenticateRequest(r, h.sessions)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !h.matchmaker.Cancel(claims.PlayerId) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (given seekRequest) seek(playerId uint64) (*Seek, error) {
+	variant := given.Variant
+	if variant == "" {
+		variant = DefaultVariant
+	}
+
+	if !ValidVariant(variant) {
+		return nil, errors.New("unknown variant")
+	}
+
+	timeControl, err := NewTimeControl(given.Clock, given.Initial, given.Increment)
+	if err != nil {
+		return nil, err
+	}
+
+	if given.MinRating < 0 || given.MaxRating < 0 || (given.MaxRating != 0 && given.MaxRating < given.MinRating) {
+		return nil, errors.New("rating range is empty")
+	}
+
+	return &Seek{
+		PlayerId:    playerId,
+		Variant:     variant,
+		TimeControl: timeControl,
+		MinRating:   given.MinRating,
+		MaxRating:   given.MaxRating,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeekCompatible(t *testing.T) {
+	seek := func(playerId uint64, rating, minRating, maxRating float64) *Seek {
+		return &Seek{
+			PlayerId:    playerId,
+			Variant:     DefaultVariant,
+			TimeControl: &TimeControl{Kind: ClockFischer, Initial: 5 * time.Minute, Increment: 3 * time.Second},
+			Rating:      rating,
+			MinRating:   minRating,
+			MaxRating:   maxRating,
+		}
+	}
+
+	other := seek(2, 1500, 0, 0)
+	otherVariant := seek(2, 1500, 0, 0)
+	otherVariant.Variant = "other"
+	untimed := seek(2, 1500, 0, 0)
+	untimed.TimeControl = nil
+	slower := seek(2, 1500, 0, 0)
+	slower.TimeControl = &TimeControl{Kind: ClockFischer, Initial: 10 * time.Minute, Increment: 3 * time.Second}
+
+	tests := []struct {
+		name       string
+		a, b       *Seek
+		compatible bool
+	}{
+		{"open seeks", seek(1, 1500, 0, 0), other, true},
+		{"same player", seek(1, 1500, 0, 0), seek(1, 1500, 0, 0), false},
+		{"different variants", seek(1, 1500, 0, 0), otherVariant, false},
+		{"timed and untimed", seek(1, 1500, 0, 0), untimed, false},
+		{"different time controls", seek(1, 1500, 0, 0), slower, false},
+		{"opponent in range", seek(1, 1500, 1400, 1600), other, true},
+		{"opponent too strong", seek(1, 1500, 0, 1450), other, false},
+		{"opponent too weak", seek(1, 1500, 1550, 0), other, false},
+		{"outside the opponent's range", seek(1, 1300, 0, 0), seek(2, 1500, 1400, 0), false},
+	}
+
+	for _, test := range tests {
+		if test.a.compatible(test.b) != test.compatible || test.b.compatible(test.a) != test.compatible {
+			t.Errorf("%s: expected compatible to be %v both ways", test.name, test.compatible)
+		}
+	}
+}
+
+func TestMatchmakerPairsSeeks(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore()}
+	matchmaker := NewMatchmaker(state)
+
+	first, err := matchmaker.Seek(&Seek{PlayerId: 1, Variant: DefaultVariant})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := matchmaker.Seek(&Seek{PlayerId: 2, Variant: DefaultVariant})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstMatch, secondMatch := <-first, <-second
+
+	if firstMatch.GameId == "" || firstMatch.GameId != secondMatch.GameId || firstMatch.Color == secondMatch.Color {
+		t.Fatalf("Seeks were not paired into one game: %+v and %+v", firstMatch, secondMatch)
+	}
+
+	if matchmaker.Cancel(1) || matchmaker.Cancel(2) {
+		t.Fatalf("Paired seeks were left in the queue")
+	}
+}
+
+func TestMatchedGameAbandonedUnlessJoined(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore()}
+
+	game, err := state.Host(HostedGameOptions{Variant: DefaultVariant, BlackSeat: 1, WhiteSeat: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.condition.L.Lock()
+	game.blackPlayer = 1
+	game.condition.L.Unlock()
+
+	state.AbandonUnlessJoined(game, 10*time.Millisecond)
+
+	select {
+	case <-game.done:
+	case <-time.After(time.Second):
+		t.Fatalf("Game was not abandoned")
+	}
+
+	game.condition.L.Lock()
+	result := game.result
+	game.condition.L.Unlock()
+
+	if result == nil || result.Reason != ReasonAbandoned || result.Winner != nil {
+		t.Fatalf("Expected the game to be abandoned, got %+v", result)
+	}
+
+	// OnGameCompleted runs just after the game is completed, under its lock
+	game.condition.L.Lock()
+	game.condition.L.Unlock()
+
+	if _, ok := state.games.Load(game.id); ok {
+		t.Fatalf("Abandoned game is still hosted")
+	}
+
+	if record, _, _ := state.store.Load(game.id); record.Result == nil || record.Result.Reason != ReasonAbandoned {
+		t.Fatalf("Abandoned game was not saved as abandoned")
+	}
+}
+
+func TestJoinedGameNotAbandoned(t *testing.T) {
+	state := &HostedGameState{store: NewMemoryGameStore()}
+
+	game, err := state.Host(HostedGameOptions{Variant: DefaultVariant, BlackSeat: 1, WhiteSeat: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.condition.L.Lock()
+	game.blackPlayer = 1
+	game.whitePlayer = 2
+	game.condition.L.Unlock()
+
+	state.AbandonUnlessJoined(game, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	game.condition.L.Lock()
+	defer game.condition.L.Unlock()
+
+	if game.completed {
+		t.Fatalf("A game both players joined was abandoned")
+	}
+}
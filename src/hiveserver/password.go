@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// Argon2id parameters for new hashes, at the minimum OWASP recommends. Each hash carries its own,
+// so raising these leaves existing hashes working.
+const (
+	PasswordTime = 2
+	// PasswordMemory is in KiB
+	PasswordMemory  = 19 * 1024
+	PasswordThreads = 1
+)
+
+const passwordKeyLength = 32
+
+// HashPassword returns a salted Argon2id hash of the password in the PHC string format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, PasswordTime, PasswordMemory, PasswordThreads, passwordKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, PasswordMemory, PasswordTime,
+		PasswordThreads, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword is true if the password is the one the hash was made from
+func CheckPassword(password, hash string) bool {
+	var version int
+	var memory, passes uint32
+	var threads uint8
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &passes, &threads); err != nil ||
+		passes == 0 || threads == 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, passes, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !CheckPassword("correct horse", hash) {
+		t.Fatalf("Password does not match its own hash %s", hash)
+	}
+
+	if CheckPassword("correct horse battery", hash) {
+		t.Fatalf("Wrong password matched")
+	}
+
+	if other, _ := HashPassword("correct horse"); other == hash {
+		t.Fatalf("Hashes are not salted")
+	}
+}
+
+func TestCheckPasswordRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdA$a2V5",
+	} {
+		if CheckPassword("", hash) {
+			t.Errorf("Malformed hash %q was accepted", hash)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PlayerIdBlockSize is how many ids are reserved in the file at a time, so that it is not written
+// for every new player. Ids left in a block when the server stops are never handed out.
+const PlayerIdBlockSize = 1000
+
+// PlayerIdAllocator hands out player ids that are never reused, even across restarts, as long as
+// it has a file to keep the highest reserved id in
+type PlayerIdAllocator struct {
+	mutex sync.Mutex
+	// empty if ids are only unique for the life of the server
+	path     string
+	next     uint64
+	reserved uint64
+}
+
+// OpenPlayerIdAllocator carries on from the ids reserved in the file at path, or keeps ids in
+// memory if path is empty
+func OpenPlayerIdAllocator(path string) (*PlayerIdAllocator, error) {
+	var reserved uint64
+
+	if path != "" {
+		contents, err := os.ReadFile(path)
+
+		if err == nil {
+			reserved, err = strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return &PlayerIdAllocator{
+		path:     path,
+		next:     reserved + 1,
+		reserved: reserved,
+	}, nil
+}
+
+// Persistent is true if ids handed out before a restart will not be handed out again
+func (allocator *PlayerIdAllocator) Persistent() bool {
+	return allocator.path != ""
+}
+
+func (allocator *PlayerIdAllocator) Next() (uint64, error) {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+
+	if allocator.next > allocator.reserved {
+		reserved := allocator.reserved + PlayerIdBlockSize
+
+		if allocator.path != "" {
+			if err := writeFileAtomically(allocator.path, []byte(strconv.FormatUint(reserved, 10))); err != nil {
+				return 0, err
+			}
+		}
+
+		allocator.reserved = reserved
+	}
+
+	id := allocator.next
+	allocator.next++
+
+	return id, nil
+}
+
+// writeFileAtomically replaces the file at path so that it holds either its old or its new
+// contents, whenever the server stops
+func writeFileAtomically(path string, contents []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(contents)
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+
+	return err
+}
@@ -10,10 +10,49 @@ const (
 	// EventDisconnect should be sent when an opponent disconnects from the game
 	EventDisconnect = "DISCONNECT"
 	// EventReconnect should be sent when an opponent reconnects to a disconnected game
-	EventReconnect     = "RECONNECT"
-	EventPlayMove      = "PLAY_MOVE"
+	EventReconnect = "RECONNECT"
+	// EventPlayMove is sent by a player to make a move, and by the server to relay a move once it
+	// has been checked and recorded
+	EventPlayMove = "PLAY_MOVE"
+	// EventAcceptedMove is sent to the player who made a move once it has been recorded
+	EventAcceptedMove  = "ACCEPT_MOVE"
 	EventRejectedMove  = "REJECT_MOVE"
 	EventGameCompleted = "GAME_COMPLETED"
+	// EventState is sent to a player whenever they connect or reconnect, so that they can rebuild
+	// the game from scratch
+	EventState = "STATE"
+	// EventResign ends the game as a loss for the player who sends it
+	EventResign = "RESIGN"
+	// EventOfferDraw is sent by a player to offer a draw, and relayed to their opponent
+	EventOfferDraw = "OFFER_DRAW"
+	// EventAcceptDraw ends the game as a draw, if the opponent has an offer open
+	EventAcceptDraw = "ACCEPT_DRAW"
+	// EventDeclineDraw is sent by a player to turn down a draw, and relayed to whoever offered it
+	EventDeclineDraw = "DECLINE_DRAW"
+	// EventRematchOffer is sent by a player once the game is over to offer to play again, and
+	// relayed to their opponent
+	EventRematchOffer = "REMATCH_OFFER"
+	// EventRematchAccept starts a new game with the colours swapped, if the opponent has an offer
+	// open
+	EventRematchAccept = "REMATCH_ACCEPT"
+	// EventRematchDecline is sent by a player to turn down a rematch, and relayed to whoever
+	// offered it
+	EventRematchDecline = "REMATCH_DECLINE"
+	// EventRematch is sent to both players once a rematch is accepted, with the game to connect to
+	EventRematch = "REMATCH"
+	// EventChat is sent by a player with a line of chat, and relayed to both players and any
+	// spectators once it has been checked
+	EventChat = "CHAT"
+)
+
+const (
+	ReasonSurrounded   = "SURROUNDED"
+	ReasonResigned     = "RESIGNED"
+	ReasonAgreedDraw   = "AGREED_DRAW"
+	ReasonDisconnected = "DISCONNECTED"
+	// ReasonAbandoned ends a game neither player came back to after the server restarted, or a
+	// matched game or rematch that was not joined in time
+	ReasonAbandoned = "ABANDONED"
 )
 
 type PlayMessage struct {
@@ -22,19 +61,51 @@ type PlayMessage struct {
 	Connect  *GameConnect  `json:"connect,omitempty"`
 	Complete *GameComplete `json:"complete,omitempty"`
 	Token    *string       `json:"token,omitempty"`
+	// Game is the state of the game as recorded by the server after a move
+	Game  *hivegame.HiveGame `json:"game,omitempty"`
+	State *GameState         `json:"state,omitempty"`
+	// Clock is the time left to each player after a move, in games with a time control
+	Clock   *ClockState  `json:"clock,omitempty"`
+	Rematch *GameRematch `json:"rematch,omitempty"`
+	Chat    *ChatMessage `json:"chat,omitempty"`
+}
+
+type GameState struct {
+	Game        hivegame.HiveGame  `json:"game"`
+	Moves       []HiveMove         `json:"moves"`
+	Color       hivegame.HiveColor `json:"color"`
+	ColorToMove hivegame.HiveColor `json:"colorToMove"`
+	Clock       *ClockState        `json:"clock,omitempty"`
+	// Chat is every line the players have sent so far
+	Chat []ChatMessage `json:"chat,omitempty"`
+	// Spectating is set for spectators, in which case Color means nothing
+	Spectating bool `json:"spectating,omitempty"`
 }
 
 type GameConnect struct {
 	Color hivegame.HiveColor `json:"color"`
 }
 
+// GameRematch is the game a rematch is played in, and the colour the player it is sent to has in
+// it
+type GameRematch struct {
+	GameId string             `json:"gameId"`
+	Color  hivegame.HiveColor `json:"color"`
+}
+
 type GameComplete struct {
-	Won bool `json:"won"`
+	Won    bool   `json:"won"`
+	Draw   bool   `json:"draw"`
+	Reason string `json:"reason"`
+	// Winner is only sent to spectators, and is nil for a draw
+	Winner *hivegame.HiveColor `json:"winner,omitempty"`
 }
 
 const (
 	MoveTypePlacement = "PLACE"
 	MoveTypeMovement  = "MOVE"
+	// MoveTypePass is announced by the server when a player has no legal moves and so passes
+	MoveTypePass = "PASS"
 )
 
 type HiveMove struct {
@@ -52,3 +123,24 @@ type HiveMovement struct {
 	From hivegame.HexVectorInt `json:"from"`
 	To   hivegame.HexVectorInt `json:"to"`
 }
+
+// valid checks that the move carries the details its type needs
+func (move *HiveMove) valid() bool {
+	if move == nil {
+		return false
+	}
+
+	switch move.MoveType {
+	case MoveTypePlacement:
+		// the game panics on piece types it has never heard of
+		return move.Placement != nil &&
+			move.Placement.PieceType >= hivegame.PieceTypeQueenBee &&
+			move.Placement.PieceType <= hivegame.PieceTypeMosquito
+	case MoveTypeMovement:
+		return move.Movement != nil
+	case MoveTypePass:
+		return true
+	}
+
+	return false
+}
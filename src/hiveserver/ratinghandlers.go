@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultLeaderboardSize = 50
+	MaxLeaderboardSize     = 100
+)
+
+type playerRatingHandler struct {
+	state *HostedGameState
+}
+
+type leaderboardHandler struct {
+	state *HostedGameState
+}
+
+type ratingResponse struct {
+	Rating      float64 `json:"rating"`
+	Deviation   float64 `json:"deviation"`
+	Volatility  float64 `json:"volatility"`
+	Games       int     `json:"games"`
+	Provisional bool    `json:"provisional"`
+}
+
+type playerRatingResponse struct {
+	PlayerId    uint64 `json:"playerId"`
+	DisplayName string `json:"displayName"`
+	// Ratings has an entry for each category the player has played in
+	Ratings map[string]ratingResponse `json:"ratings"`
+	History []RatingChange            `json:"history"`
+}
+
+type leaderboardEntry struct {
+	Rank        int     `json:"rank"`
+	PlayerId    uint64  `json:"playerId"`
+	DisplayName string  `json:"displayName"`
+	Rating      float64 `json:"rating"`
+	Deviation   float64 `json:"deviation"`
+	Games       int     `json:"games"`
+}
+
+func newRatingResponse(rating Rating) ratingResponse {
+	return ratingResponse{
+		Rating:      rating.Rating,
+		Deviation:   rating.Deviation,
+		Volatility:  rating.Volatility,
+		Games:       rating.Games,
+		Provisional: rating.Provisional(),
+	}
+}
+
+// ServeHTTP responds with a player's ratings and how they got there. A category query parameter
+// limits the history to that category.
+func (h *playerRatingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("GET %s\n", r.URL.Path)
+
+	playerId, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || playerId == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	category := r.URL.Query().Get("category")
+	if category != "" && !ValidCategory(category) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ratings := h.state.ratings
+
+	response := playerRatingResponse{
+		PlayerId:    playerId,
+		DisplayName: h.state.DisplayName(playerId),
+		Ratings:     make(map[string]ratingResponse),
+		History:     ratings.History(playerId, category),
+	}
+
+	for ratingCategory, rating := range ratings.Ratings(playerId) {
+		response.Ratings[ratingCategory] = newRatingResponse(rating)
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
+
+// ServeHTTP responds with the highest rated players in the category given by the category query
+// parameter, blitz if none is given. Players whose ratings are still provisional are left out.
+func (h *leaderboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("GET /leaderboard?%s\n", r.URL.RawQuery)
+
+	query := r.URL.Query()
+
+	category := CategoryBlitz
+	if query.Has("category") {
+		category = query.Get("category")
+
+		if !ValidCategory(category) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := DefaultLeaderboardSize
+	if query.Has("limit") {
+		var err error
+
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > MaxLeaderboardSize {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := make([]leaderboardEntry, 0)
+
+	for i, leader := range h.state.ratings.Leaderboard(category, limit) {
+		entries = append(entries, leaderboardEntry{
+			Rank:        i + 1,
+			PlayerId:    leader.PlayerId,
+			DisplayName: h.state.DisplayName(leader.PlayerId),
+			Rating:      leader.Rating.Rating,
+			Deviation:   leader.Rating.Deviation,
+			Games:       leader.Rating.Games,
+		})
+	}
+
+	err := json.NewEncoder(w).Encode(entries)
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
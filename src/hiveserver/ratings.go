@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Ratings are kept separately for each category of time control, as a player's strength at one
+// says little about the others
+const (
+	CategoryBullet    = "bullet"
+	CategoryBlitz     = "blitz"
+	CategoryRapid     = "rapid"
+	CategoryClassical = "classical"
+	CategoryUntimed   = "untimed"
+)
+
+// ProvisionalDeviation is the deviation above which a rating is provisional, and left off the
+// leaderboard
+const ProvisionalDeviation = 110.0
+
+// ValidCategory is true for any of the categories a player can be rated in
+func ValidCategory(category string) bool {
+	switch category {
+	case CategoryBullet, CategoryBlitz, CategoryRapid, CategoryClassical, CategoryUntimed:
+		return true
+	}
+
+	return false
+}
+
+// RatingCategory sorts a time control by how long a game of about 40 moves each would last
+func RatingCategory(timeControl *TimeControl) string {
+	if timeControl == nil {
+		return CategoryUntimed
+	}
+
+	estimate := timeControl.Initial + 40*timeControl.Increment
+
+	switch {
+	case estimate < 3*time.Minute:
+		return CategoryBullet
+	case estimate < 8*time.Minute:
+		return CategoryBlitz
+	case estimate < 25*time.Minute:
+		return CategoryRapid
+	default:
+		return CategoryClassical
+	}
+}
+
+// RatingChange is how a player's rating in a category moved after one game
+type RatingChange struct {
+	PlayerId   uint64  `json:"playerId"`
+	Category   string  `json:"category"`
+	GameId     string  `json:"gameId"`
+	OpponentId uint64  `json:"opponentId"`
+	Score      float64 `json:"score"`
+	Before     float64 `json:"before"`
+	After      Rating  `json:"after"`
+}
+
+type ratingKey struct {
+	playerId uint64
+	category string
+}
+
+// RatingStore keeps each player's current rating in each category along with every change that
+// led to it, appending changes to a JSON-lines file if it has one
+type RatingStore struct {
+	mutex   sync.Mutex
+	ratings map[ratingKey]Rating
+	history map[uint64][]RatingChange
+	// nil if ratings only last for the life of the server
+	file *os.File
+}
+
+// OpenRatingStore loads the rating history from the file at path, or keeps it in memory if path is
+// empty
+func OpenRatingStore(path string) (*RatingStore, error) {
+	store := &RatingStore{
+		ratings: make(map[ratingKey]Rating),
+		history: make(map[uint64][]RatingChange),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	existing, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+
+		for scanner.Scan() {
+			var change RatingChange
+
+			if err = json.Unmarshal(scanner.Bytes(), &change); err != nil {
+				_ = existing.Close()
+				return nil, err
+			}
+
+			store.apply(change)
+		}
+
+		err = scanner.Err()
+		_ = existing.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	store.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Rating is the player's rating in a category as it stands now, or a new rating if they have not
+// played in it
+func (store *RatingStore) Rating(playerId uint64, category string) Rating {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.current(playerId, category, time.Now())
+}
+
+// Ratings are the player's ratings in each category they have played in
+func (store *RatingStore) Ratings(playerId uint64) map[string]Rating {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	ratings := make(map[string]Rating)
+	now := time.Now()
+
+	for key := range store.ratings {
+		if key.playerId == playerId {
+			ratings[key.category] = store.current(playerId, key.category, now)
+		}
+	}
+
+	return ratings
+}
+
+// History lists the changes to a player's rating in a category, or in every category if it is
+// empty, oldest first
+func (store *RatingStore) History(playerId uint64, category string) []RatingChange {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	history := make([]RatingChange, 0)
+
+	for _, change := range store.history[playerId] {
+		if category == "" || change.Category == category {
+			history = append(history, change)
+		}
+	}
+
+	return history
+}
+
+// PlayerRating is a player's rating in the category of a leaderboard
+type PlayerRating struct {
+	PlayerId uint64
+	Rating   Rating
+}
+
+// Leaderboard lists up to limit players with established ratings in a category, highest first
+func (store *RatingStore) Leaderboard(category string, limit int) []PlayerRating {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var leaders []PlayerRating
+	now := time.Now()
+
+	for key := range store.ratings {
+		if key.category != category {
+			continue
+		}
+
+		rating := store.current(key.playerId, category, now)
+
+		if !rating.Provisional() {
+			leaders = append(leaders, PlayerRating{PlayerId: key.playerId, Rating: rating})
+		}
+	}
+
+	slices.SortFunc(leaders, func(a, b PlayerRating) int {
+		if order := cmp.Compare(b.Rating.Rating, a.Rating.Rating); order != 0 {
+			return order
+		}
+
+		return cmp.Compare(a.PlayerId, b.PlayerId)
+	})
+
+	if len(leaders) > limit {
+		leaders = leaders[:limit]
+	}
+
+	return leaders
+}
+
+// RateGame updates the ratings of both players of a game in its category, with blackScore 1 if
+// black won, 0 if white won and 0.5 for a draw
+func (store *RatingStore) RateGame(gameId string, category string, black, white uint64, blackScore float64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	blackRating := store.current(black, category, now)
+	whiteRating := store.current(white, category, now)
+
+	changes := []RatingChange{
+		{
+			PlayerId:   black,
+			Category:   category,
+			GameId:     gameId,
+			OpponentId: white,
+			Score:      blackScore,
+			Before:     blackRating.Rating,
+			After:      glicko2(blackRating, []glickoOutcome{{opponent: whiteRating, score: blackScore}}),
+		},
+		{
+			PlayerId:   white,
+			Category:   category,
+			GameId:     gameId,
+			OpponentId: black,
+			Score:      1 - blackScore,
+			Before:     whiteRating.Rating,
+			After:      glicko2(whiteRating, []glickoOutcome{{opponent: blackRating, score: 1 - blackScore}}),
+		},
+	}
+
+	for _, change := range changes {
+		change.After.Updated = now
+
+		if store.file != nil {
+			line, err := json.Marshal(change)
+			if err != nil {
+				return err
+			}
+
+			if _, err = store.file.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+
+		store.apply(change)
+	}
+
+	return nil
+}
+
+// current must be called while holding the store's mutex
+func (store *RatingStore) current(playerId uint64, category string, now time.Time) Rating {
+	rating, ok := store.ratings[ratingKey{playerId, category}]
+	if !ok {
+		return NewRating()
+	}
+
+	return rating.At(now)
+}
+
+// apply must be called while holding the store's mutex, or before the store is shared
+func (store *RatingStore) apply(change RatingChange) {
+	store.ratings[ratingKey{change.PlayerId, change.Category}] = change.After
+	store.history[change.PlayerId] = append(store.history[change.PlayerId], change)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxUserAgentLength is as much of a browser's user agent as is kept to tell sessions apart
+const maxUserAgentLength = 256
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// startSession signs a player in on a new session, for the device the request came from
+func startSession(r *http.Request, sessions *SessionStore, playerId uint64) (tokenResponse, error) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session, refreshToken, err := sessions.Create(playerId, userAgent)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	token, err := IssuePlayerToken(playerId, session.Id)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	return tokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+type refreshHandler struct {
+	sessions *SessionStore
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (h *refreshHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /token/refresh")
+
+	var given refreshRequest
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCredentialsBody)
+	if err := json.NewDecoder(r.Body).Decode(&given); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	session, refreshToken, err := h.sessions.Refresh(given.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error refreshing session: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token, err := IssuePlayerToken(session.PlayerId, session.Id)
+	if err != nil {
+		log.Printf("Error signing token: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(tokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
+
+type logoutHandler struct {
+	sessions *SessionStore
+}
+
+// logoutRequest picks the sessions to end. With neither field set, the session of the token used
+// for the request is ended.
+type logoutRequest struct {
+	SessionId string `json:"sessionId"`
+	All       bool   `json:"all"`
+}
+
+func (h *logoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /logout")
+
+	claims, err := AuthenticateRequest(r, h.sessions)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var given logoutRequest
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCredentialsBody)
+	if r.ContentLength != 0 {
+		if err = json.NewDecoder(r.Body).Decode(&given); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if given.All {
+		err = h.sessions.RevokeAll(claims.PlayerId)
+	} else {
+		sessionId := given.SessionId
+		if sessionId == "" {
+			sessionId = claims.SessionId
+		}
+
+		var found bool
+
+		found, err = h.sessions.Revoke(claims.PlayerId, sessionId)
+		if err == nil && !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	if err != nil {
+		log.Printf("Error revoking session: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type sessionsHandler struct {
+	sessions *SessionStore
+}
+
+type sessionResponse struct {
+	Id        string    `json:"id"`
+	UserAgent string    `json:"userAgent"`
+	Created   time.Time `json:"created"`
+	LastUsed  time.Time `json:"lastUsed"`
+	// Current is set for the session of the token used for the request
+	Current bool `json:"current"`
+}
+
+func (h *sessionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /sessions")
+
+	claims, err := AuthenticateRequest(r, h.sessions)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	response := make([]sessionResponse, 0)
+
+	for _, session := range h.sessions.ActiveFor(claims.PlayerId) {
+		response = append(response, sessionResponse{
+			Id:        session.Id,
+			UserAgent: session.UserAgent,
+			Created:   session.Created,
+			LastUsed:  session.LastUsed,
+			Current:   session.Id == claims.SessionId,
+		})
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		log.Println("Error writing JSON response")
+		return
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefreshTokenLifetime is how long a session lasts without being refreshed
+const RefreshTokenLifetime = 30 * 24 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid, expired or revoked")
+
+// Session is a player signed in on one device. Each access token carries the id of the session it
+// was issued for, and stops being accepted as soon as the session is revoked.
+type Session struct {
+	Id       string `json:"id"`
+	PlayerId uint64 `json:"playerId"`
+	// RefreshHash is the SHA-256 of the secret half of the current refresh token
+	RefreshHash string    `json:"refreshHash"`
+	UserAgent   string    `json:"userAgent"`
+	Created     time.Time `json:"created"`
+	LastUsed    time.Time `json:"lastUsed"`
+	Expires     time.Time `json:"expires"`
+	Revoked     bool      `json:"revoked"`
+}
+
+// SessionPruneInterval is how often sessions that have expired or been revoked are let go of
+const SessionPruneInterval = time.Hour
+
+// SessionStore keeps sessions by id, appending each change to a JSON-lines file if it has one, with
+// the last line for a session taking precedence. The file is compacted down to the live sessions
+// whenever it is opened, and again as dead sessions are pruned once it is mostly made of them.
+type SessionStore struct {
+	mutex    sync.Mutex
+	sessions map[string]Session
+	// watchers are called when the session they watch is revoked
+	watchers map[string]map[*sessionWatcher]bool
+	// nil if sessions only last for the life of the server
+	file *os.File
+	path string
+	// lines is the number of lines in the file
+	lines      int
+	lastPruned time.Time
+}
+
+type sessionWatcher struct {
+	onRevoke func()
+}
+
+// OpenSessionStore loads the sessions from the file at path, or keeps them in memory if path is
+// empty
+func OpenSessionStore(path string) (*SessionStore, error) {
+	store := &SessionStore{
+		sessions: make(map[string]Session),
+		watchers: make(map[string]map[*sessionWatcher]bool),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	existing, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+
+		for scanner.Scan() {
+			var session Session
+
+			if err = json.Unmarshal(scanner.Bytes(), &session); err != nil {
+				_ = existing.Close()
+				return nil, err
+			}
+
+			store.sessions[session.Id] = session
+		}
+
+		err = scanner.Err()
+		_ = existing.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	store.path = path
+	store.prune(time.Now())
+
+	if err = store.compact(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Create starts a session for a player, returning it along with its first refresh token
+func (store *SessionStore) Create(playerId uint64, userAgent string) (Session, string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	now := time.Now()
+
+	session := Session{
+		Id:          id,
+		PlayerId:    playerId,
+		RefreshHash: hashSecret(secret),
+		UserAgent:   userAgent,
+		Created:     now,
+		LastUsed:    now,
+		Expires:     now.Add(RefreshTokenLifetime),
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// guests start a session each time they join, so the dead ones have to be let go of as the
+	// server runs rather than only when it restarts
+	if now.Sub(store.lastPruned) > SessionPruneInterval {
+		store.prune(now)
+
+		if store.file != nil && store.lines > 2*len(store.sessions) {
+			if err = store.compact(); err != nil {
+				return Session{}, "", err
+			}
+		}
+	}
+
+	if err = store.save(session); err != nil {
+		return Session{}, "", err
+	}
+
+	return session, id + "." + secret, nil
+}
+
+// Refresh swaps a refresh token for a new one. A token that has already been swapped is taken as
+// stolen, and revokes its session, as only one of the thief and the player can hold the current
+// token.
+func (store *SessionStore) Refresh(refreshToken string) (Session, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+
+	newSecret, err := randomToken(32)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[id]
+	now := time.Now()
+
+	if !ok || session.Revoked || now.After(session.Expires) || session.Created.Before(tokenEpoch) {
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(session.RefreshHash)) != 1 {
+		session.Revoked = true
+
+		if err = store.save(session); err != nil {
+			return Session{}, "", err
+		}
+
+		return Session{}, "", ErrInvalidRefreshToken
+	}
+
+	session.RefreshHash = hashSecret(newSecret)
+	session.LastUsed = now
+	session.Expires = now.Add(RefreshTokenLifetime)
+
+	if err = store.save(session); err != nil {
+		return Session{}, "", err
+	}
+
+	return session, id + "." + newSecret, nil
+}
+
+// Active is true if the session exists and has been neither revoked nor left to expire
+func (store *SessionStore) Active(id string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[id]
+
+	return ok && !session.Revoked && time.Now().Before(session.Expires)
+}
+
+// Revoke ends a session of the given player. Returns false if they have no such session.
+func (store *SessionStore) Revoke(playerId uint64, id string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[id]
+	if !ok || session.PlayerId != playerId {
+		return false, nil
+	}
+
+	session.Revoked = true
+
+	return true, store.save(session)
+}
+
+// RevokeAll ends every session of the given player
+func (store *SessionStore) RevokeAll(playerId uint64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, session := range store.sessions {
+		if session.PlayerId != playerId || session.Revoked {
+			continue
+		}
+
+		session.Revoked = true
+
+		if err := store.save(session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ActiveFor lists the sessions a player is still signed in with
+func (store *SessionStore) ActiveFor(playerId uint64) []Session {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var sessions []Session
+	now := time.Now()
+
+	for _, session := range store.sessions {
+		if session.PlayerId == playerId && !session.Revoked && now.Before(session.Expires) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	return sessions
+}
+
+// Watch calls onRevoke if the session is revoked, until the returned function is called
+func (store *SessionStore) Watch(id string, onRevoke func()) func() {
+	watcher := &sessionWatcher{onRevoke: onRevoke}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.watchers[id] == nil {
+		store.watchers[id] = make(map[*sessionWatcher]bool)
+	}
+
+	store.watchers[id][watcher] = true
+
+	return func() {
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+
+		delete(store.watchers[id], watcher)
+
+		if len(store.watchers[id]) == 0 {
+			delete(store.watchers, id)
+		}
+	}
+}
+
+// save must be called while holding the store's mutex
+func (store *SessionStore) save(session Session) error {
+	if store.file != nil {
+		line, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		if _, err = store.file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+
+		store.lines++
+	}
+
+	store.sessions[session.Id] = session
+
+	if session.Revoked {
+		for watcher := range store.watchers[session.Id] {
+			watcher.onRevoke()
+		}
+
+		delete(store.watchers, session.Id)
+	}
+
+	return nil
+}
+
+// prune forgets sessions that have expired or been revoked. It must be called while holding the
+// store's mutex, or before the store is shared.
+func (store *SessionStore) prune(now time.Time) {
+	for id, session := range store.sessions {
+		if session.Revoked || now.After(session.Expires) {
+			delete(store.sessions, id)
+		}
+	}
+
+	store.lastPruned = now
+}
+
+// compact rewrites the file with a line for each session still in the store. It must be called
+// while holding the store's mutex, or before the store is shared.
+func (store *SessionStore) compact() error {
+	var compacted bytes.Buffer
+	encoder := json.NewEncoder(&compacted)
+
+	for _, session := range store.sessions {
+		if err := encoder.Encode(session); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomically(store.path, compacted.Bytes()); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(store.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	if store.file != nil {
+		_ = store.file.Close()
+	}
+
+	store.file = file
+	store.lines = len(store.sessions)
+
+	return nil
+}
+
+func randomToken(length int) (string, error) {
+	buffer := make([]byte, length)
+
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
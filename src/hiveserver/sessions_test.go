@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionRefresh(t *testing.T) {
+	store, _ := OpenSessionStore("")
+
+	session, refreshToken, err := store.Create(7, "browser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, newToken, err := store.Refresh(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshed.Id != session.Id || refreshed.PlayerId != 7 || newToken == refreshToken {
+		t.Fatalf("Refresh did not rotate the token of the same session")
+	}
+
+	// the old token being used again means one of its holders stole it
+	if _, _, err = store.Refresh(refreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("An old refresh token was accepted")
+	}
+
+	if store.Active(session.Id) {
+		t.Fatalf("Reusing a refresh token did not revoke its session")
+	}
+
+	if _, _, err = store.Refresh(newToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("A token of a revoked session was accepted")
+	}
+}
+
+func TestSessionRefreshRejectsMalformedTokens(t *testing.T) {
+	store, _ := OpenSessionStore("")
+
+	session, _, _ := store.Create(7, "browser")
+
+	for _, token := range []string{"", "nodot", session.Id + ".wrong", "unknown.secret"} {
+		if _, _, err := store.Refresh(token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh token %q was accepted", token)
+		}
+	}
+}
+
+func TestSessionRevoke(t *testing.T) {
+	store, _ := OpenSessionStore("")
+
+	first, _, _ := store.Create(7, "phone")
+	second, _, _ := store.Create(7, "laptop")
+	other, _, _ := store.Create(8, "laptop")
+
+	if found, _ := store.Revoke(8, first.Id); found {
+		t.Fatalf("Revoked another player's session")
+	}
+
+	if found, err := store.Revoke(7, first.Id); !found || err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	if store.Active(first.Id) || !store.Active(second.Id) {
+		t.Fatalf("Revoke ended the wrong sessions")
+	}
+
+	if active := store.ActiveFor(7); len(active) != 1 || active[0].Id != second.Id {
+		t.Fatalf("Expected only the second session to be active, got %+v", active)
+	}
+
+	if err := store.RevokeAll(7); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.Active(second.Id) || !store.Active(other.Id) {
+		t.Fatalf("RevokeAll ended the wrong sessions")
+	}
+}
+
+func TestSessionWatchers(t *testing.T) {
+	store, _ := OpenSessionStore("")
+
+	session, _, _ := store.Create(7, "browser")
+
+	revoked := 0
+	store.Watch(session.Id, func() { revoked++ })
+	unwatch := store.Watch(session.Id, func() { t.Fatalf("Called a watcher that had stopped") })
+	unwatch()
+
+	_ = store.RevokeAll(7)
+
+	if revoked != 1 {
+		t.Fatalf("Watcher was called %d times", revoked)
+	}
+}
+
+func TestSessionStorePrunesDeadSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.jsonl")
+
+	store, err := OpenSessionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, _, _ := store.Create(7, "browser")
+
+	for range 10 {
+		guest, _, _ := store.Create(8, "guest")
+		_, _ = store.Revoke(8, guest.Id)
+	}
+
+	store.lastPruned = time.Now().Add(-2 * SessionPruneInterval)
+
+	latest, _, _ := store.Create(9, "browser")
+
+	if len(store.sessions) != 2 {
+		t.Fatalf("Expected 2 sessions after pruning, got %d", len(store.sessions))
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := bytes.Count(contents, []byte{'\n'}); lines != 2 {
+		t.Fatalf("Expected the file to be compacted to 2 lines, got %d", lines)
+	}
+
+	reopened, err := OpenSessionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reopened.Active(live.Id) || !reopened.Active(latest.Id) || len(reopened.sessions) != 2 {
+		t.Fatalf("Sessions did not survive compaction")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"HiveServer/src/hivegame"
+)
+
+// Variants name the expansion pieces a game is played with, as in the Universal Hive Protocol
+const (
+	VariantBase            = "Base"
+	VariantMosquito        = "Base+M"
+	VariantLadybug         = "Base+L"
+	VariantMosquitoLadybug = "Base+ML"
+	DefaultVariant         = VariantMosquitoLadybug
+)
+
+// ValidVariant is true for any of the variants a hosted game can be played as
+func ValidVariant(variant string) bool {
+	switch variant {
+	case VariantBase, VariantMosquito, VariantLadybug, VariantMosquitoLadybug:
+		return true
+	}
+
+	return false
+}
+
+// CreateVariantGame starts a game with only the pieces of the given variant in each reserve
+func CreateVariantGame(variant string) hivegame.HiveGame {
+	game := hivegame.CreateHiveGame()
+
+	if variant == VariantBase || variant == VariantLadybug {
+		game.BlackReserve[hivegame.PieceTypeMosquito] = 0
+		game.WhiteReserve[hivegame.PieceTypeMosquito] = 0
+	}
+
+	if variant == VariantBase || variant == VariantMosquito {
+		game.BlackReserve[hivegame.PieceTypeLadybug] = 0
+		game.WhiteReserve[hivegame.PieceTypeLadybug] = 0
+	}
+
+	return game
+}
@@ -285,9 +285,13 @@ func placeTile(_ js.Value, args []js.Value) interface{} {
 
 	ok = game.PlaceTile(position, pieceType)
 
+	// the opponent may have been left with no legal moves, in which case they pass straight away
+	passed := ok && game.Pass()
+
 	ret := js.Global().Get("Array").New()
 	ret.Call("push", HiveGameToJsValue(game))
 	ret.Call("push", js.ValueOf(ok))
+	ret.Call("push", js.ValueOf(passed))
 
 	return ret
 }
@@ -317,9 +321,13 @@ func moveTile(_ js.Value, args []js.Value) interface{} {
 
 	ok = game.MoveTile(fromPosition, toPosition)
 
+	// the opponent may have been left with no legal moves, in which case they pass straight away
+	passed := ok && game.Pass()
+
 	ret := js.Global().Get("Array").New()
 	ret.Call("push", HiveGameToJsValue(game))
 	ret.Call("push", js.ValueOf(ok))
+	ret.Call("push", js.ValueOf(passed))
 
 	return ret
 }